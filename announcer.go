@@ -0,0 +1,582 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBandwidthLimit is the bandwidth limit, in bits per second, of a scope
+	// with no configured limit (RFC 2974 section 3.1)
+	DefaultBandwidthLimit = 4000
+
+	// MinAnnouncementInterval is the shortest base interval between two announcements of a session
+	MinAnnouncementInterval = 300 * time.Second
+
+	// deletionRepeats and deletionSpacing are the default number of times the deletions
+	// are sent when the announcer is closed, and the time between two of them
+	deletionRepeats = 3
+	deletionSpacing = time.Second
+
+	// heardTimeout is how long an announcement heard in the scope is
+	// counted towards the bandwidth used after it was last heard
+	heardTimeout = time.Hour
+
+	// minReadBackoff and maxReadBackoff bound the time the announcer waits
+	// before reading again from a connection that keeps failing
+	minReadBackoff = 5 * time.Millisecond
+	maxReadBackoff = time.Second
+)
+
+// AnnouncementInterval returns the base interval between two announcements of a session (RFC 2974 section 3.1).
+// totalSize is the size in bytes of all the announcements made in the scope, ours included,
+// which stands for the no_of_ads * ad_size of the RFC. bandwidthLimit is in bits per second.
+func AnnouncementInterval(totalSize, bandwidthLimit int) time.Duration {
+	if bandwidthLimit <= 0 {
+		bandwidthLimit = DefaultBandwidthLimit
+	}
+
+	interval := time.Duration(8*totalSize) * time.Second / time.Duration(bandwidthLimit)
+	if interval < MinAnnouncementInterval {
+		return MinAnnouncementInterval
+	}
+
+	return interval
+}
+
+// randomizeInterval adds a random offset of up to a third of the interval either way,
+// so that announcers do not synchronise (RFC 2974 section 3.1).
+func randomizeInterval(interval time.Duration) time.Duration {
+	// offset = rand(interval*2/3) - (interval/3)
+	offset := time.Duration(rand.Int63n(int64(interval*2/3)+1)) - interval/3
+
+	return interval + offset
+}
+
+// AnnouncerOption configures an Announcer created by NewAnnouncer
+type AnnouncerOption func(*Announcer)
+
+// Set the session announcement bandwidth limit of the scope, in bits per second
+func WithBandwidthLimit(bitsPerSecond int) AnnouncerOption {
+	return func(a *Announcer) {
+		a.bandwidthLimit = bitsPerSecond
+	}
+}
+
+// Compute the message id hash of the sessions with the strategy, instead of using that of their packet
+func WithAnnouncerHashStrategy(s HashStrategy) AnnouncerOption {
+	return func(a *Announcer) {
+		a.hashStrategy = s
+	}
+}
+
+// Send the deletions of the sessions count times, spacing apart, when the announcer is closed.
+// A count of zero or less sends no deletions.
+func WithDeletionRetries(count int, spacing time.Duration) AnnouncerOption {
+	return func(a *Announcer) {
+		a.deletionRepeats = count
+		a.deletionSpacing = spacing
+	}
+}
+
+// Announcer periodically sends the announcements of a set of sessions to a SAP group.
+//
+// The interval between announcements follows RFC 2974 section 3.1 and depends on
+// the announcements heard in the scope. The Announcer learns about them by reading from its
+// connection, so the connection should be bound to the SAP group it sends to.
+type Announcer struct {
+	conn           net.PacketConn
+	group          net.Addr
+	bandwidthLimit int
+	hashStrategy   HashStrategy
+
+	deletionRepeats int
+	deletionSpacing time.Duration
+
+	mu       sync.Mutex
+	sessions map[*Session]struct{}
+	heard    map[AnnouncementKey]heardAnnouncement
+
+	// writeMu orders the writes, so that the deletion of a version is not sent before one of its announcements.
+	// It is held before mu.
+	writeMu sync.Mutex
+
+	wake      chan struct{}
+	closing   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Session is an announced session, as returned by Announcer.Add.
+type Session struct {
+	announcer *Announcer
+	packet    Packet
+	data      []byte
+	next      time.Time
+}
+
+// Packet returns the packet announced for the session.
+func (s *Session) Packet() Packet {
+	s.announcer.mu.Lock()
+	defer s.announcer.mu.Unlock()
+
+	return s.packet
+}
+
+type heardAnnouncement struct {
+	size     int
+	lastSeen time.Time
+}
+
+// NewAnnouncer returns an Announcer sending to group over conn. It starts announcing right away,
+// and keeps doing so until Close is called. The Announcer takes ownership of conn.
+func NewAnnouncer(conn net.PacketConn, group net.Addr, opts ...AnnouncerOption) *Announcer {
+	a := &Announcer{
+		conn:            conn,
+		group:           group,
+		bandwidthLimit:  DefaultBandwidthLimit,
+		deletionRepeats: deletionRepeats,
+		deletionSpacing: deletionSpacing,
+		sessions:        map[*Session]struct{}{},
+		heard:           map[AnnouncementKey]heardAnnouncement{},
+		wake:            make(chan struct{}, 1),
+		closing:         make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	go a.listen()
+	go a.run()
+
+	return a
+}
+
+// Add starts announcing the packet. The first announcement is sent right away.
+// The message id hash of the packet is changed if another session of the same source already uses it.
+func (a *Announcer) Add(p Packet) (*Session, error) {
+	for {
+		a.mu.Lock()
+		closing := a.isClosing()
+		hash, err := a.uniqueHash(p)
+		a.mu.Unlock()
+
+		if closing {
+			return nil, errAnnouncerClosed
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.MessageIDHash = hash
+
+		// Marshal without holding the lock, signing can be slow
+		data, err := p.Marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		a.mu.Lock()
+		if a.isClosing() {
+			a.mu.Unlock()
+			return nil, errAnnouncerClosed
+		}
+
+		if a.hashUsed(p) {
+			// Another session took the hash in the meantime
+			a.mu.Unlock()
+			continue
+		}
+
+		s := &Session{announcer: a, packet: p, data: data}
+		a.sessions[s] = struct{}{}
+		a.signal()
+		a.mu.Unlock()
+
+		return s, nil
+	}
+}
+
+// Update replaces the packet announced for the session. The new version is sent right away,
+// followed by a deletion of the previous version so listeners do not keep both (RFC 2974 section 5).
+// A Directory that heard the new version reports it as modified and ignores the deletion.
+// Its message id hash is changed if it is that of the previous version or of another session of the same source.
+func (a *Announcer) Update(s *Session, p Packet) error {
+	for {
+		a.mu.Lock()
+		_, ok := a.sessions[s]
+		previous := s.packet
+		hash, err := a.uniqueHash(p)
+		a.mu.Unlock()
+
+		if !ok {
+			return errUnknownSession
+		}
+		if err != nil {
+			return err
+		}
+		p.MessageIDHash = hash
+
+		data, err := p.Marshal()
+		if err != nil {
+			return err
+		}
+
+		deletion, err := deletionOf(previous).Marshal()
+		if err != nil {
+			return err
+		}
+
+		a.writeMu.Lock()
+		a.mu.Lock()
+		if _, ok := a.sessions[s]; !ok {
+			a.mu.Unlock()
+			a.writeMu.Unlock()
+			return errUnknownSession
+		}
+
+		if s.packet.MessageIDHash != previous.MessageIDHash || a.hashUsed(p) {
+			// The session was updated or another session took the hash in the meantime
+			a.mu.Unlock()
+			a.writeMu.Unlock()
+			continue
+		}
+
+		now := time.Now()
+		s.packet = p
+		s.data = data
+		s.next = now.Add(randomizeInterval(AnnouncementInterval(a.totalSize(now), a.bandwidthLimit)))
+		a.signal()
+		a.mu.Unlock()
+
+		// Write errors are not fatal, the new version is announced again at the next interval
+		// and listeners time the previous version out
+		_, _ = a.conn.WriteTo(data, a.group)
+		_, _ = a.conn.WriteTo(deletion, a.group)
+		a.writeMu.Unlock()
+
+		return nil
+	}
+}
+
+// Update announces payload as the new version of the session, see Announcer.Update.
+// The packet is otherwise the same, and gets a new message id hash.
+func (s *Session) Update(payload []byte) error {
+	a := s.announcer
+
+	a.mu.Lock()
+	p := *s.packet.Clone()
+	a.mu.Unlock()
+
+	p.Payload = payload
+	p.MessageIDHash = 0
+
+	return a.Update(s, p)
+}
+
+// uniqueHash returns the message id hash to announce p with. It is computed with the hash strategy
+// of the announcer if it has one, or taken from p, and then increased until no session of the same
+// source uses it. a.mu must be held.
+func (a *Announcer) uniqueHash(p Packet) (uint16, error) {
+	hash := p.MessageIDHash
+	if a.hashStrategy != nil {
+		hash = a.hashStrategy.MessageIDHash(p.Payload)
+	} else if hash == 0 {
+		hash = p.messageIDHash()
+	}
+
+	source := p.Source()
+	used := make(map[uint16]struct{}, len(a.sessions))
+	for s := range a.sessions {
+		if s.packet.Source() == source {
+			used[s.packet.MessageIDHash] = struct{}{}
+		}
+	}
+
+	for i := 0; i < 1<<16; i++ {
+		if _, ok := used[hash]; !ok && hash != 0 {
+			return hash, nil
+		}
+		hash++
+	}
+
+	return 0, errHashesExhausted
+}
+
+// hashUsed reports whether a session of the same source as p is announced with its message id hash.
+// a.mu must be held.
+func (a *Announcer) hashUsed(p Packet) bool {
+	source := p.Source()
+	for s := range a.sessions {
+		if s.packet.Source() == source && s.packet.MessageIDHash == p.MessageIDHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Remove stops announcing the session.
+func (a *Announcer) Remove(s *Session) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.sessions[s]; !ok {
+		return errUnknownSession
+	}
+
+	delete(a.sessions, s)
+	a.signal()
+
+	return nil
+}
+
+// Delete stops announcing the session and sends its deletion,
+// so that listeners forget it without waiting for it to time out.
+func (a *Announcer) Delete(s *Session) error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+
+	if err := a.Remove(s); err != nil {
+		return err
+	}
+
+	// The session is not updated anymore once removed
+	deletion, err := deletionOf(s.packet).Marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = a.conn.WriteTo(deletion, a.group)
+
+	return err
+}
+
+// Close stops announcing, sends the deletions of the sessions and closes the connection.
+// Deletions are sent a few times as they may be lost, see WithDeletionRetries.
+// It waits for the announcer to stop and the deletions to be sent until ctx is done.
+// The connection is closed in any case, and the errors of the deletions that could not be marshaled are returned.
+func (a *Announcer) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() {
+		a.mu.Lock()
+		close(a.closing)
+		a.mu.Unlock()
+	})
+
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		a.conn.Close()
+		return ctx.Err()
+	}
+
+	if err := a.sendDeletions(ctx); err != nil {
+		a.conn.Close()
+		return err
+	}
+
+	return a.conn.Close()
+}
+
+// sendDeletions sends the deletions of the sessions and forgets about them.
+func (a *Announcer) sendDeletions(ctx context.Context) error {
+	a.mu.Lock()
+	packets := make([]Packet, 0, len(a.sessions))
+	for s := range a.sessions {
+		packets = append(packets, s.packet)
+	}
+	a.sessions = map[*Session]struct{}{}
+	a.mu.Unlock()
+
+	// The deletions that cannot be marshaled are reported once the others are sent
+	var errs []error
+	deletions := make([][]byte, 0, len(packets))
+	for _, p := range packets {
+		data, err := deletionOf(p).Marshal()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deletions = append(deletions, data)
+	}
+
+	if len(deletions) == 0 {
+		return errors.Join(errs...)
+	}
+
+	for i := 0; i < a.deletionRepeats; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(a.deletionSpacing):
+			case <-ctx.Done():
+				return errors.Join(append(errs, ctx.Err())...)
+			}
+		}
+
+		for _, data := range deletions {
+			// Write errors are not fatal, listeners time the sessions out
+			_, _ = a.conn.WriteTo(data, a.group)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// run sends the announcements as they come due.
+func (a *Announcer) run() {
+	defer close(a.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		a.writeMu.Lock()
+		a.mu.Lock()
+		due, next := a.sendDue(time.Now())
+		a.mu.Unlock()
+
+		// Write without holding the lock, so a slow socket does not block the other methods
+		for _, data := range due {
+			// Write errors are not fatal, the announcement is retried at the next interval
+			_, _ = a.conn.WriteTo(data, a.group)
+		}
+		a.writeMu.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		var expired <-chan time.Time
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+			expired = timer.C
+		}
+
+		select {
+		case <-expired:
+		case <-a.wake:
+		case <-a.closing:
+			return
+		}
+	}
+}
+
+// sendDue schedules the next transmission of the announcements that are due and returns them to be sent,
+// along with the time the next announcement is due, or the zero time if there are no sessions.
+// a.mu must be held.
+func (a *Announcer) sendDue(now time.Time) (due [][]byte, next time.Time) {
+	totalSize := a.totalSize(now)
+
+	for s := range a.sessions {
+		if !s.next.After(now) {
+			due = append(due, s.data)
+			s.next = now.Add(randomizeInterval(AnnouncementInterval(totalSize, a.bandwidthLimit)))
+		}
+
+		if next.IsZero() || s.next.Before(next) {
+			next = s.next
+		}
+	}
+
+	return due, next
+}
+
+// totalSize returns the size of all the announcements made in the scope, ours included.
+// a.mu must be held.
+func (a *Announcer) totalSize(now time.Time) int {
+	total := 0
+
+	own := make(map[AnnouncementKey]struct{}, len(a.sessions))
+	for s := range a.sessions {
+		own[s.packet.AnnouncementKey()] = struct{}{}
+		total += len(s.data)
+	}
+
+	for key, heard := range a.heard {
+		if now.Sub(heard.lastSeen) > heardTimeout {
+			delete(a.heard, key)
+			continue
+		}
+
+		if _, ok := own[key]; !ok {
+			total += heard.size
+		}
+	}
+
+	return total
+}
+
+// listen reads the announcements made in the scope, to account for the bandwidth they use.
+func (a *Announcer) listen() {
+	buf := make([]byte, 65536)
+	backoff := time.Duration(0)
+
+	for {
+		n, _, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || a.isClosing() {
+				return
+			}
+
+			// Wait before reading again rather than spinning on an error that persists
+			if backoff = 2 * backoff; backoff < minReadBackoff {
+				backoff = minReadBackoff
+			} else if backoff > maxReadBackoff {
+				backoff = maxReadBackoff
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-a.closing:
+				return
+			}
+			continue
+		}
+		backoff = 0
+
+		h := Header{}
+		if err := h.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+
+		a.mu.Lock()
+		if h.MessageType == Deletion {
+			delete(a.heard, h.AnnouncementKey())
+		} else {
+			a.heard[h.AnnouncementKey()] = heardAnnouncement{size: n, lastSeen: time.Now()}
+		}
+		a.mu.Unlock()
+	}
+}
+
+// deletionOf returns the deletion of the announcement p, carrying the same message id hash, source and payload.
+func deletionOf(p Packet) Packet {
+	WithMessageType(Deletion)(&p.Header)
+
+	return p
+}
+
+// signal wakes up the sending loop.
+func (a *Announcer) signal() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (a *Announcer) isClosing() bool {
+	select {
+	case <-a.closing:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,539 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAnnouncementInterval(t *testing.T) {
+	testCases := []struct {
+		name           string
+		totalSize      int
+		bandwidthLimit int
+		want           time.Duration
+	}{
+		{
+			name:           "Test 1: Minimum Interval",
+			totalSize:      1000,
+			bandwidthLimit: DefaultBandwidthLimit,
+			want:           MinAnnouncementInterval,
+		},
+		{
+			name:           "Test 2: Busy Scope",
+			totalSize:      200000,
+			bandwidthLimit: DefaultBandwidthLimit,
+			want:           400 * time.Second,
+		},
+		{
+			name:           "Test 3: Higher Bandwidth Limit",
+			totalSize:      200000,
+			bandwidthLimit: 8000,
+			want:           MinAnnouncementInterval,
+		},
+		{
+			name:           "Test 4: No Bandwidth Limit Falls Back To Default",
+			totalSize:      200000,
+			bandwidthLimit: 0,
+			want:           400 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := AnnouncementInterval(tc.totalSize, tc.bandwidthLimit)
+			if tc.want != got {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRandomizeInterval(t *testing.T) {
+	interval := 300 * time.Second
+
+	for i := 0; i < 1000; i++ {
+		got := randomizeInterval(interval)
+		if got < 200*time.Second || got > 400*time.Second {
+			t.Fatalf("expected an interval between 200s and 400s, got %v", got)
+		}
+	}
+}
+
+// readPacket reads and unmarshals the next packet received on conn.
+func readPacket(t *testing.T, conn net.PacketConn) *Packet {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline failed with error: %v", err)
+	}
+
+	buf := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom failed with error: %v", err)
+	}
+
+	p := &Packet{}
+	if err := p.Unmarshal(buf[:n]); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	return p
+}
+
+func TestAnnouncer(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr())
+
+	p, err := NewPacket([]byte("v=0\r\ns=First\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	s, err := a.Add(p)
+	if err != nil {
+		t.Fatalf("Add failed with error: %v", err)
+	}
+
+	if got := readPacket(t, group); string(got.Payload) != "v=0\r\ns=First\r\n" {
+		t.Errorf("unexpected payload %q", got.Payload)
+	}
+
+	p2, err := NewPacket([]byte("v=0\r\ns=Second\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	if err := a.Update(s, p2); err != nil {
+		t.Fatalf("Update failed with error: %v", err)
+	}
+
+	// The new version is announced before the previous one is deleted
+	if got := readPacket(t, group); got.MessageType != Announcement || string(got.Payload) != "v=0\r\ns=Second\r\n" {
+		t.Errorf("unexpected payload %q", got.Payload)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Deletion || got.MessageIDHash != p.MessageIDHash || string(got.Payload) != "v=0\r\ns=First\r\n" {
+		t.Errorf("unexpected deletion %v", got)
+	}
+
+	if err := s.Update([]byte("v=0\r\ns=Third\r\n")); err != nil {
+		t.Fatalf("Update failed with error: %v", err)
+	}
+
+	if got := readPacket(t, group); string(got.Payload) != "v=0\r\ns=Third\r\n" || got.MessageIDHash == p2.MessageIDHash {
+		t.Errorf("unexpected announcement %v", got)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Deletion || got.MessageIDHash != p2.MessageIDHash {
+		t.Errorf("unexpected deletion %v", got)
+	}
+
+	if err := a.Remove(s); err != nil {
+		t.Fatalf("Remove failed with error: %v", err)
+	}
+
+	if err := a.Remove(s); err != errUnknownSession {
+		t.Errorf("Expected error %v, but got %v", errUnknownSession, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := a.Close(ctx); err != nil {
+		t.Fatalf("Close failed with error: %v", err)
+	}
+
+	if _, err := a.Add(p); err != errAnnouncerClosed {
+		t.Errorf("Expected error %v, but got %v", errAnnouncerClosed, err)
+	}
+}
+
+func TestAnnouncerCloseSendsDeletions(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr(), WithDeletionRetries(2, 10*time.Millisecond))
+
+	p, err := NewPacket([]byte("v=0\r\ns=First\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	if _, err := a.Add(p); err != nil {
+		t.Fatalf("Add failed with error: %v", err)
+	}
+	readPacket(t, group)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := a.Close(ctx); err != nil {
+		t.Fatalf("Close failed with error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		got := readPacket(t, group)
+		if got.MessageType != Deletion || got.MessageIDHash != p.MessageIDHash || got.Source() != p.Source() {
+			t.Errorf("unexpected deletion %v", got)
+		}
+	}
+}
+
+func TestAnnouncerTotalSize(t *testing.T) {
+	now := time.Now()
+	own := CreateMockPacket(Packet{Payload: []byte("v=0\r\n")})
+
+	a := &Announcer{
+		sessions: map[*Session]struct{}{
+			{packet: *own, data: make([]byte, 100)}: {},
+		},
+		heard: map[AnnouncementKey]heardAnnouncement{
+			// Our own announcement, looped back
+			own.AnnouncementKey(): {size: 100, lastSeen: now},
+			{Source: netip.MustParseAddr("192.0.2.2"), MessageIDHash: 1}: {size: 200, lastSeen: now},
+			{Source: netip.MustParseAddr("192.0.2.3"), MessageIDHash: 2}: {size: 400, lastSeen: now.Add(-2 * heardTimeout)},
+		},
+	}
+
+	if got := a.totalSize(now); got != 300 {
+		t.Errorf("expected %d, got %d", 300, got)
+	}
+
+	if _, ok := a.heard[AnnouncementKey{Source: netip.MustParseAddr("192.0.2.3"), MessageIDHash: 2}]; ok {
+		t.Error("expected the stale announcement to be forgotten")
+	}
+}
+
+func TestAnnouncerUniqueHash(t *testing.T) {
+	first := CreateMockPacket(Packet{Header: Header{MessageIDHash: 10}})
+	second := CreateMockPacket(Packet{Header: Header{MessageIDHash: 11}})
+	other := CreateMockPacket(Packet{Header: Header{MessageIDHash: 12, OriginatingSource: net.ParseIP("192.0.2.2")}})
+
+	a := &Announcer{
+		sessions: map[*Session]struct{}{
+			{packet: *first}:  {},
+			{packet: *second}: {},
+			{packet: *other}:  {},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		strategy HashStrategy
+		hash     uint16
+		want     uint16
+	}{
+		{
+			name: "Unused",
+			hash: 20,
+			want: 20,
+		},
+		{
+			name: "UsedBySameSource",
+			hash: 10,
+			want: 12,
+		},
+		{
+			name:     "Strategy",
+			strategy: CRCHash{},
+			hash:     10,
+			want:     CRCHash{}.MessageIDHash(first.Payload),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a.hashStrategy = tc.strategy
+
+			p := *first
+			p.MessageIDHash = tc.hash
+
+			got, err := a.uniqueHash(p)
+			if err != nil {
+				t.Fatalf("uniqueHash failed with error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSessionPacketDuringUpdate(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr())
+	defer a.Close(context.Background())
+
+	p, err := NewPacket([]byte("v=0\r\ns=First\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	s, err := a.Add(p)
+	if err != nil {
+		t.Fatalf("Add failed with error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_ = s.Packet()
+		}
+	}()
+
+	if err := s.Update([]byte("v=0\r\ns=Second\r\n")); err != nil {
+		t.Fatalf("Update failed with error: %v", err)
+	}
+	<-done
+
+	if got := s.Packet(); string(got.Payload) != "v=0\r\ns=Second\r\n" {
+		t.Errorf("unexpected payload %q", got.Payload)
+	}
+}
+
+func TestAnnouncerDelete(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr())
+	defer a.Close(context.Background())
+
+	p, err := NewPacket([]byte("v=0\r\ns=First\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	s, err := a.Add(p)
+	if err != nil {
+		t.Fatalf("Add failed with error: %v", err)
+	}
+	readPacket(t, group)
+
+	if err := a.Delete(s); err != nil {
+		t.Fatalf("Delete failed with error: %v", err)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Deletion || got.MessageIDHash != p.MessageIDHash {
+		t.Errorf("unexpected deletion %v", got)
+	}
+
+	if err := a.Delete(s); err != errUnknownSession {
+		t.Errorf("Expected error %v, but got %v", errUnknownSession, err)
+	}
+}
+
+// onceSigner signs the first packet and fails to sign the next ones
+type onceSigner struct {
+	signed bool
+}
+
+func (s *onceSigner) AuthType() AuthType {
+	return PGP
+}
+
+func (s *onceSigner) Sign([]byte) ([]byte, error) {
+	if s.signed {
+		return nil, errSignFailed
+	}
+	s.signed = true
+
+	return []byte{1, 2, 3, 4}, nil
+}
+
+var errSignFailed = errors.New("sign failed")
+
+func TestAnnouncerCloseReportsDeletionErrors(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr(), WithDeletionRetries(1, 0))
+
+	signed, err := NewPacket([]byte("v=0\r\ns=Signed\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, WithSigner(&onceSigner{}))
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	p, err := NewPacket([]byte("v=0\r\ns=Unsigned\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	for _, packet := range []Packet{signed, p} {
+		if _, err := a.Add(packet); err != nil {
+			t.Fatalf("Add failed with error: %v", err)
+		}
+		readPacket(t, group)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// The deletion of the signed session cannot be signed, the other one is still sent
+	if err := a.Close(ctx); !errors.Is(err, errSignFailed) {
+		t.Errorf("Expected error %v, but got %v", errSignFailed, err)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Deletion || string(got.Payload) != "v=0\r\ns=Unsigned\r\n" {
+		t.Errorf("unexpected deletion %v", got)
+	}
+}
+
+func TestAnnouncerUpdateModifiesSession(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer conn.Close()
+
+	l := NewListener([]net.PacketConn{conn})
+	events := make(chan EventType, 10)
+	l.Directory().Subscribe(func(e Event) {
+		events <- e.Type
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Run(ctx)
+
+	announcerConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(announcerConn, conn.LocalAddr(), WithDeletionRetries(1, 0))
+
+	p, err := NewPacket([]byte("v=0\r\no=- 1234 1 IN IP4 192.0.2.1\r\ns=First\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	s, err := a.Add(p)
+	if err != nil {
+		t.Fatalf("Add failed with error: %v", err)
+	}
+
+	expectEvent := func(want EventType) {
+		t.Helper()
+
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %v", want)
+		}
+	}
+
+	expectEvent(SessionAdded)
+
+	if err := s.Update([]byte("v=0\r\no=- 1234 2 IN IP4 192.0.2.1\r\ns=Second\r\n")); err != nil {
+		t.Fatalf("Update failed with error: %v", err)
+	}
+
+	// The deletion of the first version that follows the update is ignored
+	expectEvent(SessionModified)
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer closeCancel()
+
+	if err := a.Close(closeCtx); err != nil {
+		t.Fatalf("Close failed with error: %v", err)
+	}
+
+	expectEvent(SessionDeleted)
+
+	if n := len(l.Directory().Sessions()); n != 0 {
+		t.Errorf("expected no sessions, got %d", n)
+	}
+}
+
+// failingConn is a connection whose reads always fail
+type failingConn struct {
+	net.PacketConn
+	reads atomic.Int32
+}
+
+func (c *failingConn) ReadFrom([]byte) (int, net.Addr, error) {
+	c.reads.Add(1)
+
+	return 0, nil, errors.New("read failed")
+}
+
+func (c *failingConn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	return len(b), nil
+}
+
+func (c *failingConn) Close() error {
+	return nil
+}
+
+func TestAnnouncerReadErrors(t *testing.T) {
+	conn := &failingConn{}
+	a := NewAnnouncer(conn, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: Port})
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The reads are spaced out instead of spinning
+	if reads := conn.reads.Load(); reads > 10 {
+		t.Errorf("expected at most 10 reads, got %d", reads)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := a.Close(ctx); err != nil {
+		t.Fatalf("Close failed with error: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package sap
+
+import (
+	"encoding/binary"
+)
+
+// The format of the signature carried in the authentication header
+type AuthType uint8
+
+const (
+	PGP AuthType = 0
+	CMS AuthType = 1
+)
+
+// AuthHeader represents the authentication header carried in the
+// authentication data of an SAP packet (RFC 2974 section 7)
+type AuthHeader struct {
+	// The version number of the authentication format, MUST be set to 1
+	Version uint8
+
+	// If the padding bit is set to 1 the authentication data has been padded
+	// to a multiple of 32 bits, and the last byte gives the number of padding bytes
+	// (including itself) that must be discarded.
+	// It is set on unmarshaled headers, Marshal pads whenever it is needed.
+	Padding uint8
+
+	// The format of the signature, PGP or CMS
+	AuthType AuthType
+
+	// The format specific authentication subheader, the signature itself
+	Signature []byte
+}
+
+const (
+	authVersionShift = 5    // Number of bits to shift for the authentication version
+	authPaddingShift = 4    // Number of bits to shift for the authentication padding bit
+	authVersionMask  = 0x07 // Mask for the 3 bit authentication version
+	authTypeMask     = 0x0F // Mask for the 4 bit authentication type
+)
+
+// MarshalSize returns the size of the authentication header once marshaled, padding included.
+func (a AuthHeader) MarshalSize() int {
+	// First byte with the version, padding bit and authentication type
+	size := 1 + len(a.Signature)
+
+	// Padding to a multiple of 32 bits
+	if size%4 != 0 {
+		size += 4 - size%4
+	}
+
+	return size
+}
+
+// Marshal serializes the authentication header into 32 bit words,
+// ready to be used as the AuthenticationData of a Header.
+func (a AuthHeader) Marshal() ([]uint32, error) {
+	/*
+	    0                   1                   2                   3
+	    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	   | V=1 |P| Auth  |                                               |
+	   +-+-+-+-+-+-+-+-+                                               |
+	   |              Format  specific authentication subheader        |
+	   :                        ..................                     :
+	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	*/
+
+	size := a.MarshalSize()
+	if size/4 > 0xFF {
+		// The authentication length is an 8 bit field
+		return nil, errAuthDataTooLarge
+	}
+
+	buf := make([]byte, size)
+
+	// The first three bits are the version
+	buf[0] = (a.Version & authVersionMask) << authVersionShift
+
+	// The fourth bit is the padding bit
+	padLen := size - 1 - len(a.Signature)
+	if padLen > 0 {
+		buf[0] |= 1 << authPaddingShift
+	}
+
+	// The last four bits are the authentication type
+	buf[0] |= byte(a.AuthType) & authTypeMask
+
+	copy(buf[1:], a.Signature)
+
+	if padLen > 0 {
+		buf[size-1] = byte(padLen)
+	}
+
+	return authDataWords(buf), nil
+}
+
+// Unmarshal parses the authentication data of a Header and stores the result in the AuthHeader.
+func (a *AuthHeader) Unmarshal(data []uint32) error {
+	if len(data) == 0 {
+		return ErrNoAuthHeader
+	}
+
+	buf := authDataBytes(data)
+
+	a.Version = (buf[0] >> authVersionShift) & authVersionMask
+	a.Padding = (buf[0] >> authPaddingShift) & oneBitMask
+	a.AuthType = AuthType(buf[0] & authTypeMask)
+
+	signature := buf[1:]
+	if a.Padding == 1 {
+		padLen := int(buf[len(buf)-1])
+		if padLen == 0 || padLen > len(signature) {
+			return ErrInvalidAuthPadding
+		}
+		signature = signature[:len(signature)-padLen]
+	}
+
+	a.Signature = signature
+
+	return nil
+}
+
+// AuthHeader parses the authentication data of the header.
+func (h Header) AuthHeader() (AuthHeader, error) {
+	a := AuthHeader{}
+	err := a.Unmarshal(h.AuthenticationData)
+
+	return a, err
+}
+
+// SetAuthHeader marshals the authentication header into the authentication data
+// and sets the authentication length accordingly.
+func (h *Header) SetAuthHeader(a AuthHeader) error {
+	data, err := a.Marshal()
+	if err != nil {
+		return err
+	}
+
+	h.AuthenticationData = data
+	h.AuthenticationLength = uint8(len(data))
+
+	return nil
+}
+
+// authDataBytes converts authentication data to its network byte order representation.
+func authDataBytes(data []uint32) []byte {
+	buf := make([]byte, len(data)*4)
+	for i, word := range data {
+		binary.BigEndian.PutUint32(buf[i*4:], word)
+	}
+
+	return buf
+}
+
+// authDataWords converts bytes in network byte order to authentication data.
+// The length of buf must be a multiple of 4.
+func authDataWords(buf []byte) []uint32 {
+	data := make([]uint32, len(buf)/4)
+	for i := range data {
+		data[i] = binary.BigEndian.Uint32(buf[i*4:])
+	}
+
+	return data
+}
@@ -0,0 +1,120 @@
+package sap
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAuthHeaderMarshalAndUnmarshal checks that an authentication header can be marshaled and then unmarshaled to its original state.
+func TestAuthHeaderMarshalAndUnmarshal(t *testing.T) {
+	testCases := []struct {
+		name       string
+		authHeader AuthHeader
+		wantWords  int
+	}{
+		{
+			name:       "Test 1: PGP Without Padding",
+			authHeader: AuthHeader{Version: 1, AuthType: PGP, Signature: []byte{1, 2, 3}},
+			wantWords:  1,
+		},
+		{
+			name:       "Test 2: CMS With Padding",
+			authHeader: AuthHeader{Version: 1, Padding: 1, AuthType: CMS, Signature: []byte{1, 2, 3, 4, 5}},
+			wantWords:  2,
+		},
+		{
+			name:       "Test 3: Single Padding Byte",
+			authHeader: AuthHeader{Version: 1, Padding: 1, AuthType: CMS, Signature: []byte{1, 2}},
+			wantWords:  1,
+		},
+		{
+			name:       "Test 4: Empty Signature",
+			authHeader: AuthHeader{Version: 1, Padding: 1, AuthType: PGP, Signature: []byte{}},
+			wantWords:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := CreateMockHeader(Header{})
+			if err := h.SetAuthHeader(tc.authHeader); err != nil {
+				t.Fatalf("SetAuthHeader failed with error: %v", err)
+			}
+
+			if int(h.AuthenticationLength) != tc.wantWords || len(h.AuthenticationData) != tc.wantWords {
+				t.Errorf("expected %d words, got %d", tc.wantWords, h.AuthenticationLength)
+			}
+
+			data, err := h.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			h2 := Header{}
+			if err := h2.Unmarshal(data); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			got, err := h2.AuthHeader()
+			if err != nil {
+				t.Fatalf("AuthHeader failed with error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.authHeader, got) {
+				t.Errorf("expected %+v, got %+v", tc.authHeader, got)
+			}
+		})
+	}
+}
+
+// TestAuthHeaderLayout checks the bit layout of the first byte of the authentication header.
+func TestAuthHeaderLayout(t *testing.T) {
+	data, err := AuthHeader{Version: 1, AuthType: CMS, Signature: []byte{0xAA, 0xBB}}.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	// V=1, P=1, Auth=1, the signature and one padding byte
+	if want := []uint32{0x31AABB01}; !reflect.DeepEqual(want, data) {
+		t.Errorf("expected %#x, got %#x", want, data)
+	}
+}
+
+func TestAuthHeaderErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         []uint32
+		expectedError error
+	}{
+		{
+			name:          "NoAuthHeader",
+			input:         nil,
+			expectedError: ErrNoAuthHeader,
+		},
+		{
+			name:          "ZeroPadding",
+			input:         []uint32{0x30000000},
+			expectedError: ErrInvalidAuthPadding,
+		},
+		{
+			name:          "PaddingLongerThanData",
+			input:         []uint32{0x30000005},
+			expectedError: ErrInvalidAuthPadding,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := AuthHeader{}
+			err := a.Unmarshal(tc.input)
+			if err != tc.expectedError {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+
+	h := CreateMockHeader(Header{})
+	if err := h.SetAuthHeader(AuthHeader{Version: 1, Signature: make([]byte, 1024)}); err != errAuthDataTooLarge {
+		t.Errorf("Expected error %v, but got %v", errAuthDataTooLarge, err)
+	}
+}
@@ -0,0 +1,251 @@
+// Command sapannounce announces SDP files until it is interrupted or terminated, then deletes their sessions.
+//
+// Usage:
+//
+//	sapannounce [flags] file.sdp...
+//
+// Each session is announced on the SAP group of the scope of its connection address,
+// at the interval RFC 2974 sets for the bandwidth used by the announcements heard in the scope.
+//
+// The flags are:
+//
+//	-source 192.0.2.1
+//		Originating source of the announcements, the first global unicast address
+//		of the interface, or of the host if no interface is given, if empty.
+//	-ipv6
+//		Use an IPv6 originating source.
+//	-payload-type application/sdp
+//		Payload type sent in the announcements, omitted if empty.
+//	-interface eth0
+//		Interface to join the SAP groups on, the system default if empty.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+	"github.com/pion/sdp/v3"
+)
+
+// closeTimeout is how long the deletions are sent for when interrupted
+const closeTimeout = 5 * time.Second
+
+func main() {
+	source := flag.String("source", "", "originating source, the first global unicast address of the interface or host if empty")
+	ipv6 := flag.Bool("ipv6", false, "use an IPv6 originating source")
+	payloadType := flag.String("payload-type", "", "payload type sent in the announcements, omitted if empty")
+	interfaceName := flag.String("interface", "", "interface to join the SAP groups on, the system default if empty")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: sapannounce [flags] file.sdp...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var ifi *net.Interface
+	if *interfaceName != "" {
+		var err error
+		if ifi, err = net.InterfaceByName(*interfaceName); err != nil {
+			fatal(err)
+		}
+	}
+
+	originatingSource, err := sourceAddr(*source, *ipv6, ifi)
+	if err != nil {
+		fatal(err)
+	}
+
+	var opts []sap.PacketOption
+	if *payloadType != "" {
+		opts = append(opts, sap.WithPayloadType(*payloadType))
+	}
+
+	announcers := map[string]*sap.Announcer{}
+	for _, name := range flag.Args() {
+		payload, group, err := load(name)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", name, err))
+		}
+
+		a, ok := announcers[group.String()]
+		if !ok {
+			conn, err := sap.JoinGroup(group, ifi)
+			if err != nil {
+				fatal(fmt.Errorf("joining %v: %w", group, err))
+			}
+			a = sap.NewAnnouncer(conn, group)
+			announcers[group.String()] = a
+		}
+
+		p, err := sap.NewPacket(payload, net.UDPAddr{IP: originatingSource}, opts...)
+		if err != nil {
+			fatal(err)
+		}
+
+		if _, err := a.Add(p); err != nil {
+			fatal(fmt.Errorf("%s: %w", name, err))
+		}
+
+		fmt.Fprintf(os.Stderr, "sapannounce: announcing %s on %v\n", name, group)
+	}
+
+	// Service managers stop the process with SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	for group, err := range closeAll(closeCtx, announcers) {
+		fmt.Fprintf(os.Stderr, "sapannounce: closing %s: %v\n", group, err)
+	}
+}
+
+// closeAll closes the announcers at the same time, so that each sends all its deletions before ctx is done.
+// It returns the errors by group.
+func closeAll(ctx context.Context, announcers map[string]*sap.Announcer) map[string]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = map[string]error{}
+	)
+
+	for group, a := range announcers {
+		wg.Add(1)
+		go func(group string, a *sap.Announcer) {
+			defer wg.Done()
+
+			if err := a.Close(ctx); err != nil {
+				mu.Lock()
+				errs[group] = err
+				mu.Unlock()
+			}
+		}(group, a)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// load reads an SDP file and returns its content with CRLF line endings, along with the SAP group of the session
+func load(name string) ([]byte, *net.UDPAddr, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload := crlf(data)
+
+	desc := &sdp.SessionDescription{}
+	if err := desc.Unmarshal(payload); err != nil {
+		return nil, nil, err
+	}
+
+	group, err := sessionGroup(desc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return payload, group, nil
+}
+
+// sessionGroup returns the SAP group of the session, from its connection address or that of its first media
+func sessionGroup(desc *sdp.SessionDescription) (*net.UDPAddr, error) {
+	c := desc.ConnectionInformation
+	for _, media := range desc.MediaDescriptions {
+		if c != nil {
+			break
+		}
+		c = media.ConnectionInformation
+	}
+
+	if c == nil || c.Address == nil {
+		return nil, errors.New("session has no connection address")
+	}
+
+	// The address may be followed by a TTL and a number of addresses
+	host, _, _ := strings.Cut(c.Address.Address, "/")
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("connection address %q is not an IP address", c.Address.Address)
+	}
+
+	return sap.GroupFor(ip)
+}
+
+// crlf turns the line endings of data into CRLF, as SDP requires
+func crlf(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// sourceAddr parses the originating source, or picks the first global unicast address
+// of the address family of the interface, or of the host if ifi is nil
+func sourceAddr(source string, ipv6 bool, ifi *net.Interface) (net.IP, error) {
+	if source != "" {
+		ip := net.ParseIP(source)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IP address", source)
+		}
+		if (ip.To4() == nil) != ipv6 {
+			return nil, fmt.Errorf("originating source %v is not of the requested address family", ip)
+		}
+		return ip, nil
+	}
+
+	var addrs []net.Addr
+	var err error
+	if ifi != nil {
+		addrs, err = ifi.Addrs()
+	} else {
+		addrs, err = net.InterfaceAddrs()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ip := globalUnicast(addrs, ipv6); ip != nil {
+		return ip, nil
+	}
+
+	if ifi != nil {
+		return nil, fmt.Errorf("no global unicast address found on %s, set the originating source with -source", ifi.Name)
+	}
+
+	return nil, errors.New("no global unicast address found, set the originating source with -source")
+}
+
+// globalUnicast returns the first global unicast address of addrs in the address family, nil if there is none
+func globalUnicast(addrs []net.Addr, ipv6 bool) net.IP {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.IsGlobalUnicast() && (ipNet.IP.To4() == nil) == ipv6 {
+			return ipNet.IP
+		}
+	}
+
+	return nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "sapannounce:", err)
+	os.Exit(1)
+}
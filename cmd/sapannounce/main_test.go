@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+	"github.com/pion/sdp/v3"
+)
+
+func TestSessionGroup(t *testing.T) {
+	testCases := []struct {
+		name        string
+		sdp         string
+		want        string
+		expectError bool
+	}{
+		{
+			name: "SessionConnection",
+			sdp:  "v=0\no=- 1 1 IN IP4 192.0.2.1\ns=Test\nc=IN IP4 239.69.1.1/32\nt=0 0\n",
+			want: "239.255.255.255:9875",
+		},
+		{
+			name: "MediaConnection",
+			sdp:  "v=0\no=- 1 1 IN IP4 192.0.2.1\ns=Test\nt=0 0\nm=audio 5004 RTP/AVP 97\nc=IN IP4 224.2.1.1/127\n",
+			want: "224.2.127.254:9875",
+		},
+		{
+			name:        "NoConnection",
+			sdp:         "v=0\no=- 1 1 IN IP4 192.0.2.1\ns=Test\nt=0 0\n",
+			expectError: true,
+		},
+		{
+			name:        "Unicast",
+			sdp:         "v=0\no=- 1 1 IN IP4 192.0.2.1\ns=Test\nc=IN IP4 192.0.2.2\nt=0 0\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			desc := &sdp.SessionDescription{}
+			if err := desc.Unmarshal(crlf([]byte(tc.sdp))); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			got, err := sessionGroup(desc)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("sessionGroup failed with error: %v", err)
+			}
+			if got.String() != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	if got := string(crlf([]byte("v=0\r\ns=Test\nt=0 0\n"))); got != "v=0\r\ns=Test\r\nt=0 0\r\n" {
+		t.Errorf("unexpected line endings %q", got)
+	}
+}
+
+func TestGlobalUnicast(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("192.0.2.1"), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(64, 128)},
+	}
+
+	testCases := []struct {
+		name  string
+		addrs []net.Addr
+		ipv6  bool
+		want  net.IP
+	}{
+		{name: "IPv4", addrs: addrs, want: net.ParseIP("192.0.2.1")},
+		{name: "IPv6", addrs: addrs, ipv6: true, want: net.ParseIP("2001:db8::1")},
+		{name: "NoGlobalAddress", addrs: addrs[:2]},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := globalUnicast(tc.addrs, tc.ipv6); !got.Equal(tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCloseAll(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	announcers := map[string]*sap.Announcer{}
+	for i := 0; i < 3; i++ {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("ListenPacket failed with error: %v", err)
+		}
+
+		a := sap.NewAnnouncer(conn, group.LocalAddr(), sap.WithDeletionRetries(3, 100*time.Millisecond))
+		p, err := sap.NewPacket([]byte(fmt.Sprintf("v=0\r\ns=Session %d\r\n", i)), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+		if err != nil {
+			t.Fatalf("NewPacket failed with error: %v", err)
+		}
+		if _, err := a.Add(p); err != nil {
+			t.Fatalf("Add failed with error: %v", err)
+		}
+
+		announcers[fmt.Sprint(i)] = a
+	}
+
+	// Each announcer takes 200ms to send its deletions, closing them one after another would time out
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	if errs := closeAll(ctx, announcers); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
@@ -0,0 +1,364 @@
+// Command sapdump joins SAP groups and prints the packets it receives.
+//
+// Usage:
+//
+//	sapdump [flags]
+//
+// The flags are:
+//
+//	-scopes global,local
+//		Comma separated SAP groups to join, given as scope names or addresses. The scope names are
+//		global, local and org for IPv4, and ipv6-link, ipv6-admin, ipv6-site, ipv6-org and ipv6-global.
+//	-interfaces eth0,eth1
+//		Comma separated interfaces to join the groups on, the system default if empty.
+//	-format text
+//		Output format, text as printed by Packet.String, json with one object per line and
+//		the payloads that are not UTF-8 in base64, or sdp to print the SDP description of announcements only.
+//	-source, -payload-type, -message-type
+//		Only print the packets with the given originating source, payload type or
+//		message type, which is announcement or deletion.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/openaudiocollective/sap"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// scopes maps the scope names to an address of the scope, whose SAP group is found with sap.GroupFor
+var scopes = map[string]string{
+	"global":      "224.2.0.1",
+	"local":       "239.255.0.1",
+	"org":         "239.192.0.1",
+	"ipv6-link":   "ff02::1",
+	"ipv6-admin":  "ff04::1",
+	"ipv6-site":   "ff05::1",
+	"ipv6-org":    "ff08::1",
+	"ipv6-global": "ff0e::1",
+}
+
+type filter struct {
+	source      netip.Addr
+	payloadType string
+	messageType string
+}
+
+func main() {
+	scopeList := flag.String("scopes", "global,local", "comma separated scope names or SAP group addresses to join")
+	interfaceList := flag.String("interfaces", "", "comma separated interfaces to join the groups on, the system default if empty")
+	format := flag.String("format", "text", "output format: text, json or sdp")
+	source := flag.String("source", "", "only print packets from this originating source")
+	payloadType := flag.String("payload-type", "", "only print packets with this payload type")
+	messageType := flag.String("message-type", "", "only print packets of this message type: announcement or deletion")
+	flag.Parse()
+
+	f := filter{payloadType: *payloadType, messageType: *messageType}
+	if *source != "" {
+		addr, err := netip.ParseAddr(*source)
+		if err != nil {
+			fatal(err)
+		}
+		f.source = addr.Unmap()
+	}
+
+	if f.messageType != "" && f.messageType != "announcement" && f.messageType != "deletion" {
+		fatal(fmt.Errorf("unknown message type %q", f.messageType))
+	}
+
+	var printer func(io.Writer, received) error
+	switch *format {
+	case "text":
+		printer = printText
+	case "json":
+		printer = printJSON
+	case "sdp":
+		printer = printSDP
+	default:
+		fatal(fmt.Errorf("unknown format %q", *format))
+	}
+
+	conns, err := join(split(*scopeList), split(*interfaceList))
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *net.UDPConn) {
+			defer wg.Done()
+
+			read(conn, func(r received) {
+				if !f.match(r.packet) {
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err := printer(os.Stdout, r); err != nil {
+					fatal(err)
+				}
+			})
+		}(conn)
+	}
+	wg.Wait()
+}
+
+// join joins the groups on the interfaces, with one connection per group
+// so that a packet received on several interfaces is read once.
+func join(groups, interfaces []string) ([]*net.UDPConn, error) {
+	ifis := []*net.Interface{nil}
+	if len(interfaces) > 0 {
+		ifis = ifis[:0]
+		for _, name := range interfaces {
+			ifi, err := net.InterfaceByName(name)
+			if err != nil {
+				return nil, err
+			}
+			ifis = append(ifis, ifi)
+		}
+	}
+
+	var conns []*net.UDPConn
+	for _, name := range groups {
+		group, err := groupAddr(name)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := sap.JoinGroup(group, ifis[0])
+		if err != nil {
+			return nil, fmt.Errorf("joining %v: %w", group, err)
+		}
+		conns = append(conns, conn)
+
+		for _, ifi := range ifis[1:] {
+			if err := joinInterface(conn, group, ifi); err != nil {
+				return nil, fmt.Errorf("joining %v on %s: %w", group, ifi.Name, err)
+			}
+		}
+	}
+
+	return conns, nil
+}
+
+// joinInterface joins the group on another interface with the connection
+func joinInterface(conn *net.UDPConn, group *net.UDPAddr, ifi *net.Interface) error {
+	if group.IP.To4() != nil {
+		return ipv4.NewPacketConn(conn).JoinGroup(ifi, group)
+	}
+
+	return ipv6.NewPacketConn(conn).JoinGroup(ifi, group)
+}
+
+// groupAddr returns the SAP group of a scope name, or the group address itself
+func groupAddr(name string) (*net.UDPAddr, error) {
+	if addr, ok := scopes[name]; ok {
+		return sap.GroupFor(net.ParseIP(addr))
+	}
+
+	ip := net.ParseIP(name)
+	if ip == nil || !ip.IsMulticast() {
+		return nil, fmt.Errorf("%q is neither a scope name nor a multicast address", name)
+	}
+
+	return &net.UDPAddr{IP: ip, Port: sap.Port}, nil
+}
+
+type received struct {
+	time   time.Time
+	from   net.Addr
+	packet *sap.Packet
+}
+
+// read calls handle with every packet read from conn, until it is closed
+func read(conn *net.UDPConn, handle func(received)) {
+	buf := make([]byte, 65536)
+
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintln(os.Stderr, "sapdump:", err)
+			}
+			return
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+
+		p := &sap.Packet{}
+		if err := p.Unmarshal(data); err != nil {
+			fmt.Fprintf(os.Stderr, "sapdump: packet from %v: %v\n", from, err)
+			continue
+		}
+
+		handle(received{time: time.Now(), from: from, packet: p})
+	}
+}
+
+func (f filter) match(p *sap.Packet) bool {
+	if f.source.IsValid() && p.Source() != f.source {
+		return false
+	}
+
+	if f.payloadType != "" && !sameMediaType(payloadType(p), f.payloadType) {
+		return false
+	}
+
+	if f.messageType != "" && messageType(p) != f.messageType {
+		return false
+	}
+
+	return true
+}
+
+func printText(w io.Writer, r received) error {
+	_, err := fmt.Fprintf(w, "%s from %v\n%v\n", r.time.Format(time.RFC3339Nano), r.from, r.packet)
+
+	return err
+}
+
+type jsonPacket struct {
+	Time                 time.Time `json:"time"`
+	From                 string    `json:"from"`
+	Version              uint8     `json:"version"`
+	MessageType          string    `json:"messageType"`
+	Source               string    `json:"source"`
+	MessageIDHash        uint16    `json:"messageIdHash"`
+	Encrypted            bool      `json:"encrypted"`
+	Compressed           bool      `json:"compressed"`
+	AuthenticationLength uint8     `json:"authenticationLength"`
+	Undecryptable        bool      `json:"undecryptable,omitempty"`
+	PayloadType          string    `json:"payloadType,omitempty"`
+	PayloadTypeSource    string    `json:"payloadTypeSource"`
+	Payload              string    `json:"payload,omitempty"`
+	PayloadEncoding      string    `json:"payloadEncoding,omitempty"`
+}
+
+func printJSON(w io.Writer, r received) error {
+	p := r.packet
+
+	out := jsonPacket{
+		Time:                 r.time,
+		From:                 r.from.String(),
+		Version:              p.Version,
+		MessageType:          messageType(p),
+		Source:               p.Source().String(),
+		MessageIDHash:        p.MessageIDHash,
+		Encrypted:            p.Encrypted == 1,
+		Compressed:           p.Compressed == 1,
+		AuthenticationLength: p.AuthenticationLength,
+		Undecryptable:        p.Undecryptable(),
+		PayloadTypeSource:    p.PayloadTypeSource.String(),
+	}
+
+	if !p.Undecryptable() {
+		out.PayloadType = payloadType(p)
+		out.Payload = string(p.Payload)
+
+		// Binary payloads would not survive the conversion to a JSON string
+		if !utf8.Valid(p.Payload) {
+			out.Payload = base64.StdEncoding.EncodeToString(p.Payload)
+			out.PayloadEncoding = "base64"
+		}
+	}
+
+	return json.NewEncoder(w).Encode(out)
+}
+
+// printSDP prints the payload of SDP announcements, followed by an empty line
+func printSDP(w io.Writer, r received) error {
+	p := r.packet
+	if p.MessageType != sap.Announcement || p.Undecryptable() || !sameMediaType(payloadType(p), sap.SDPPayloadType) {
+		return nil
+	}
+
+	payload := strings.TrimRight(string(p.Payload), "\r\n")
+	_, err := fmt.Fprintf(w, "%s\n\n", payload)
+
+	return err
+}
+
+// payloadType returns the payload type of the packet, application/sdp if it was omitted
+// in front of an SDP description, or unknown if there is no payload to tell
+func payloadType(p *sap.Packet) string {
+	switch {
+	case p.PayloadType != "":
+		return p.PayloadType
+	case p.PayloadTypeSource == sap.PayloadTypeImplicit || p.PayloadTypeSource == sap.PayloadTypeQuirk:
+		return sap.SDPPayloadType
+	case p.Version == 0 && len(p.Payload) > 0:
+		// SAPv0 packets carry SDP descriptions and have no payload type
+		return sap.SDPPayloadType
+	default:
+		return "unknown"
+	}
+}
+
+func messageType(p *sap.Packet) string {
+	if p.MessageType == sap.Deletion {
+		return "deletion"
+	}
+
+	return "announcement"
+}
+
+// sameMediaType compares two media types, ignoring case and parameters
+func sameMediaType(a, b string) bool {
+	if mediaType, _, err := mime.ParseMediaType(a); err == nil {
+		a = mediaType
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(b); err == nil {
+		b = mediaType
+	}
+
+	return strings.EqualFold(a, b)
+}
+
+// split splits a comma separated list, ignoring empty items
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "sapdump:", err)
+	os.Exit(1)
+}
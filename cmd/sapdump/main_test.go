@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/openaudiocollective/sap"
+)
+
+// receive returns the packet as sapdump reads it
+func receive(t *testing.T, payload []byte, opts ...sap.PacketOption) *sap.Packet {
+	t.Helper()
+
+	p, err := sap.NewPacket(payload, net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, opts...)
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	received := &sap.Packet{}
+	if err := received.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	return received
+}
+
+func TestFilter(t *testing.T) {
+	p := receive(t, []byte("v=0\r\n"), sap.WithMessageType(sap.Deletion))
+
+	testCases := []struct {
+		name   string
+		filter filter
+		want   bool
+	}{
+		{
+			name: "NoFilter",
+			want: true,
+		},
+		{
+			name:   "Source",
+			filter: filter{source: netip.MustParseAddr("192.0.2.1")},
+			want:   true,
+		},
+		{
+			name:   "OtherSource",
+			filter: filter{source: netip.MustParseAddr("192.0.2.2")},
+		},
+		{
+			name:   "ImplicitPayloadType",
+			filter: filter{payloadType: "Application/SDP"},
+			want:   true,
+		},
+		{
+			name:   "OtherPayloadType",
+			filter: filter{payloadType: "application/x-aes67-json"},
+		},
+		{
+			name:   "MessageType",
+			filter: filter{messageType: "deletion"},
+			want:   true,
+		},
+		{
+			name:   "OtherMessageType",
+			filter: filter{messageType: "announcement"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.match(p); got != tc.want {
+				t.Errorf("expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGroupAddr(t *testing.T) {
+	testCases := []struct {
+		name        string
+		want        string
+		expectError bool
+	}{
+		{name: "global", want: "224.2.127.254:9875"},
+		{name: "local", want: "239.255.255.255:9875"},
+		{name: "org", want: "239.195.255.255:9875"},
+		{name: "ipv6-site", want: "[ff05::2:7ffe]:9875"},
+		{name: "239.255.255.255", want: "239.255.255.255:9875"},
+		{name: "192.0.2.1", expectError: true},
+		{name: "nowhere", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := groupAddr(tc.name)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("groupAddr failed with error: %v", err)
+			}
+			if got.String() != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestJoinOneConnectionPerGroup(t *testing.T) {
+	conns, err := join([]string{"local", "239.255.255.254"}, []string{"lo", "eth0"})
+	if err != nil {
+		t.Skipf("joining the groups failed, the interfaces may not support multicast: %v", err)
+	}
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	if len(conns) != 2 {
+		t.Errorf("expected one connection per group, got %d", len(conns))
+	}
+}
+
+func TestPayloadType(t *testing.T) {
+	testCases := []struct {
+		name   string
+		packet *sap.Packet
+		want   string
+	}{
+		{
+			name:   "Explicit",
+			packet: receive(t, []byte("{}"), sap.WithPayloadType("application/json")),
+			want:   "application/json",
+		},
+		{
+			name:   "Implicit",
+			packet: receive(t, []byte("v=0\r\n")),
+			want:   sap.SDPPayloadType,
+		},
+		{
+			name:   "NoPayload",
+			packet: receive(t, nil),
+			want:   "unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := payloadType(tc.packet); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		payload  []byte
+		want     string
+		encoding string
+	}{
+		{
+			name:    "Text",
+			payload: []byte("v=0\r\n"),
+			want:    "v=0\r\n",
+		},
+		{
+			name:     "Binary",
+			payload:  []byte{0xFF, 0x00, 0xFE},
+			want:     "/wD+",
+			encoding: "base64",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := receive(t, tc.payload, sap.WithPayloadType("application/octet-stream"))
+
+			var buf bytes.Buffer
+			if err := printJSON(&buf, received{from: &net.UDPAddr{}, packet: p}); err != nil {
+				t.Fatalf("printJSON failed with error: %v", err)
+			}
+
+			var out jsonPacket
+			if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			if out.Payload != tc.want || out.PayloadEncoding != tc.encoding {
+				t.Errorf("expected payload %q encoded as %q, got %q encoded as %q", tc.want, tc.encoding, out.Payload, out.PayloadEncoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Command sapreplay resends the SAP packets of a pcap or pcapng capture with their original timing.
+//
+// Usage:
+//
+//	sapreplay [flags] capture.pcap
+//
+// The flags are:
+//
+//	-speed 1
+//		Replay speed, 2 sending the packets twice as fast as recorded, 0 without waiting.
+//	-source 192.0.2.1
+//		Rewrite the originating source of the packets, which must be of the same address family.
+//	-destination 239.255.255.255:9875
+//		Send the packets to this address instead of their recorded destination.
+//	-local 192.0.2.2
+//		Local address to send from, which picks the interface multicast packets are sent on.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+
+	"github.com/openaudiocollective/sap/pcap"
+	"github.com/openaudiocollective/sap/replay"
+)
+
+// config holds the command line of sapreplay
+type config struct {
+	speed       float64
+	source      string
+	destination string
+	local       string
+	capture     string
+}
+
+// errUsage is returned by parseFlags when the command line does not name a single capture
+var errUsage = errors.New("usage: sapreplay [flags] capture.pcap")
+
+func main() {
+	c, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	opts, err := c.options()
+	if err != nil {
+		fatal(err)
+	}
+
+	laddr, err := c.localAddr()
+	if err != nil {
+		fatal(err)
+	}
+
+	f, err := os.Open(c.capture)
+	if err != nil {
+		fatal(err)
+	}
+	defer f.Close()
+
+	r, err := pcap.NewReader(f)
+	if err != nil {
+		fatal(err)
+	}
+
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	sent, err := replay.Replay(ctx, r, conn, opts...)
+	fmt.Fprintf(os.Stderr, "sapreplay: sent %d packets\n", sent)
+	if err != nil && ctx.Err() == nil {
+		fatal(err)
+	}
+}
+
+// parseFlags parses the command line arguments, without the command name, printing the usage to output on errors
+func parseFlags(args []string, output io.Writer) (config, error) {
+	c := config{}
+
+	fs := flag.NewFlagSet("sapreplay", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Float64Var(&c.speed, "speed", 1, "replay speed, 2 sending the packets twice as fast as recorded, 0 without waiting")
+	fs.StringVar(&c.source, "source", "", "rewrite the originating source of the packets")
+	fs.StringVar(&c.destination, "destination", "", "send the packets to this address instead of their recorded destination")
+	fs.StringVar(&c.local, "local", "", "local address to send from, which picks the interface multicast packets are sent on")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), errUsage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return config{}, errUsage
+	}
+	c.capture = fs.Arg(0)
+
+	return c, nil
+}
+
+// options returns the replay options of the command line
+func (c config) options() ([]replay.Option, error) {
+	opts := []replay.Option{replay.WithSpeed(c.speed)}
+
+	if c.source != "" {
+		addr, err := netip.ParseAddr(c.source)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, replay.WithSource(addr))
+	}
+
+	if c.destination != "" {
+		addr, err := net.ResolveUDPAddr("udp", c.destination)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, replay.WithDestination(addr))
+	}
+
+	return opts, nil
+}
+
+// localAddr returns the address to send from, any address if -local is not set
+func (c config) localAddr() (*net.UDPAddr, error) {
+	laddr := &net.UDPAddr{}
+	if c.local != "" {
+		if laddr.IP = net.ParseIP(c.local); laddr.IP == nil {
+			return nil, fmt.Errorf("%q is not an IP address", c.local)
+		}
+	}
+
+	return laddr, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "sapreplay:", err)
+	os.Exit(1)
+}
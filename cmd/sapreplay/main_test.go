@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+	"github.com/openaudiocollective/sap/pcap"
+	"github.com/openaudiocollective/sap/replay"
+)
+
+func TestParseFlags(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		want          config
+		expectedError error
+	}{
+		{
+			name: "Defaults",
+			args: []string{"capture.pcap"},
+			want: config{speed: 1, capture: "capture.pcap"},
+		},
+		{
+			name: "AllFlags",
+			args: []string{"-speed", "0", "-source", "192.0.2.1", "-destination", "239.255.255.255:9875", "-local", "192.0.2.2", "capture.pcapng"},
+			want: config{source: "192.0.2.1", destination: "239.255.255.255:9875", local: "192.0.2.2", capture: "capture.pcapng"},
+		},
+		{
+			name:          "NoCapture",
+			args:          []string{"-speed", "2"},
+			expectedError: errUsage,
+		},
+		{
+			name:          "TwoCaptures",
+			args:          []string{"first.pcap", "second.pcap"},
+			expectedError: errUsage,
+		},
+		{
+			name:          "Help",
+			args:          []string{"-h"},
+			expectedError: flag.ErrHelp,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFlags(tc.args, io.Discard)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := parseFlags([]string{"-speed", "fast", "capture.pcap"}, io.Discard); err == nil {
+		t.Errorf("expected an error for an invalid speed")
+	}
+}
+
+type singleRecord struct {
+	record *pcap.Record
+}
+
+func (r *singleRecord) Next() (pcap.Record, error) {
+	if r.record == nil {
+		return pcap.Record{}, io.EOF
+	}
+
+	record := *r.record
+	r.record = nil
+
+	return record, nil
+}
+
+func TestOptions(t *testing.T) {
+	target, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer target.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer conn.Close()
+
+	p, err := sap.NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	c := config{speed: 1, source: "198.51.100.7", destination: target.LocalAddr().String()}
+	opts, err := c.options()
+	if err != nil {
+		t.Fatalf("options failed with error: %v", err)
+	}
+
+	// The recorded destination is replaced by -destination
+	r := &singleRecord{record: &pcap.Record{Timestamp: time.Now(), Destination: netip.MustParseAddrPort("192.0.2.9:9875"), Data: data}}
+	if _, err := replay.Replay(context.Background(), r, conn, opts...); err != nil {
+		t.Fatalf("Replay failed with error: %v", err)
+	}
+
+	if err := target.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline failed with error: %v", err)
+	}
+
+	buf := make([]byte, 1500)
+	n, _, err := target.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom failed with error: %v", err)
+	}
+
+	got := sap.Packet{}
+	if err := got.Unmarshal(buf[:n]); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+	if want := netip.MustParseAddr("198.51.100.7"); got.Source() != want {
+		t.Errorf("expected source %v, got %v", want, got.Source())
+	}
+}
+
+func TestOptionsErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		c    config
+	}{
+		{name: "InvalidSource", c: config{source: "192.0.2"}},
+		{name: "InvalidDestination", c: config{destination: "239.255.255.255:port"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if opts, err := tc.c.options(); err == nil {
+				t.Errorf("expected an error, got %d options", len(opts))
+			}
+		})
+	}
+}
+
+func TestLocalAddr(t *testing.T) {
+	laddr, err := config{local: "192.0.2.2"}.localAddr()
+	if err != nil {
+		t.Fatalf("localAddr failed with error: %v", err)
+	}
+	if !laddr.IP.Equal(net.ParseIP("192.0.2.2")) || laddr.Port != 0 {
+		t.Errorf("unexpected local address %v", laddr)
+	}
+
+	if laddr, err := (config{}).localAddr(); err != nil || laddr.IP != nil {
+		t.Errorf("expected any address, got %v and error %v", laddr, err)
+	}
+
+	if laddr, err := (config{local: "here"}).localAddr(); err == nil {
+		t.Errorf("expected an error, got %v", laddr)
+	}
+}
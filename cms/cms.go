@@ -0,0 +1,285 @@
+// Package cms signs and verifies SAP packets using CMS (PKCS#7) detached signatures with X.509 certificates
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/openaudiocollective/sap"
+)
+
+var (
+	errUnsupportedKey       = errors.New("cms: unsupported private key type")
+	errNotSignedData        = errors.New("cms: signature is not CMS signed data")
+	errNoSigner             = errors.New("cms: signed data has no signer")
+	errUntrustedSigner      = errors.New("cms: signer is not a trusted certificate")
+	errUnsupportedAlgorithm = errors.New("cms: unsupported signature algorithm")
+	errDigestMismatch       = errors.New("cms: message digest does not match the signed data")
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSA             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// ASN.1 structures of RFC 5652
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// Signer produces CMS detached signatures.
+//
+// The signer is identified by the issuer and serial number of its certificate.
+// The certificate itself is left out to fit the 1020 bytes available in the
+// authentication header, listeners are expected to hold it already.
+type Signer struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// NewSigner returns a Signer signing with key, the private key of cert.
+// RSA, ECDSA and Ed25519 keys are supported.
+func NewSigner(cert *x509.Certificate, key crypto.Signer) (*Signer, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, errUnsupportedKey
+	}
+
+	return &Signer{cert: cert, key: key}, nil
+}
+
+// AuthType returns sap.CMS
+func (s *Signer) AuthType() sap.AuthType {
+	return sap.CMS
+}
+
+// Sign returns the DER encoded CMS signed data holding the detached signature of data.
+func (s *Signer) Sign(data []byte) ([]byte, error) {
+	info := signerInfo{
+		Version: 1,
+		SID: issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: s.cert.RawIssuer},
+			SerialNumber: s.cert.SerialNumber,
+		},
+	}
+
+	var err error
+	switch s.key.(type) {
+	case *rsa.PrivateKey:
+		info.DigestAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+		info.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue}
+		digest := sha256.Sum256(data)
+		info.Signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+
+	case *ecdsa.PrivateKey:
+		info.DigestAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+		info.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+		digest := sha256.Sum256(data)
+		info.Signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+
+	case ed25519.PrivateKey:
+		// RFC 8419, Ed25519 signs the data itself
+		info.DigestAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSHA512}
+		info.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidEd25519}
+		info.Signature, err = s.key.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{info.DigestAlgorithm},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidData},
+		SignerInfos:      []signerInfo{info},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// Verifier checks CMS detached signatures against a set of trusted certificates.
+type Verifier struct {
+	trusted []*x509.Certificate
+}
+
+// NewVerifier returns a Verifier accepting signatures made with the keys of the trusted certificates.
+func NewVerifier(trusted ...*x509.Certificate) *Verifier {
+	return &Verifier{trusted: trusted}
+}
+
+// AuthType returns sap.CMS
+func (v *Verifier) AuthType() sap.AuthType {
+	return sap.CMS
+}
+
+// Verify checks that signature, a DER encoded CMS signed data, is a detached signature of data
+// by a trusted certificate. Every signer of the signed data must be trusted.
+func (v *Verifier) Verify(data, signature []byte) error {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(signature, &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+		return errNotSignedData
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return errNotSignedData
+	}
+
+	if len(sd.SignerInfos) == 0 {
+		return errNoSigner
+	}
+
+	for _, info := range sd.SignerInfos {
+		if err := v.verifySigner(data, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifySigner checks the signature of a single signer.
+func (v *Verifier) verifySigner(data []byte, info signerInfo) error {
+	cert := v.find(info.SID)
+	if cert == nil {
+		return errUntrustedSigner
+	}
+
+	algorithm, err := signatureAlgorithm(info)
+	if err != nil {
+		return err
+	}
+
+	signed := data
+	if len(info.SignedAttrs.FullBytes) > 0 {
+		// With signed attributes the signature covers their DER encoding as a SET OF,
+		// and the data is bound to it by the message digest attribute
+		if err := checkMessageDigest(data, info); err != nil {
+			return err
+		}
+
+		signed = append([]byte{0x31}, info.SignedAttrs.FullBytes[1:]...)
+	}
+
+	return cert.CheckSignature(algorithm, signed, info.Signature)
+}
+
+// find returns the trusted certificate identified by sid, or nil.
+func (v *Verifier) find(sid issuerAndSerialNumber) *x509.Certificate {
+	for _, cert := range v.trusted {
+		if bytes.Equal(cert.RawIssuer, sid.Issuer.FullBytes) && cert.SerialNumber.Cmp(sid.SerialNumber) == 0 {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// signatureAlgorithm maps the digest and signature algorithms of info to an x509.SignatureAlgorithm.
+func signatureAlgorithm(info signerInfo) (x509.SignatureAlgorithm, error) {
+	digest := info.DigestAlgorithm.Algorithm
+	sig := info.SignatureAlgorithm.Algorithm
+
+	switch {
+	case (sig.Equal(oidRSA) || sig.Equal(oidSHA256WithRSA)) && digest.Equal(oidSHA256):
+		return x509.SHA256WithRSA, nil
+	case sig.Equal(oidECDSAWithSHA256) && digest.Equal(oidSHA256):
+		return x509.ECDSAWithSHA256, nil
+	case sig.Equal(oidEd25519):
+		return x509.PureEd25519, nil
+	}
+
+	return x509.UnknownSignatureAlgorithm, errUnsupportedAlgorithm
+}
+
+// checkMessageDigest compares the message digest signed attribute with the digest of data.
+func checkMessageDigest(data []byte, info signerInfo) error {
+	if !info.DigestAlgorithm.Algorithm.Equal(oidSHA256) {
+		return errUnsupportedAlgorithm
+	}
+
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(info.SignedAttrs.FullBytes, &attrs, "set,tag:0"); err != nil {
+		return errNotSignedData
+	}
+
+	digest := sha256.Sum256(data)
+	for _, attr := range attrs {
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+
+		var value []byte
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &value); err != nil {
+			return errNotSignedData
+		}
+		if !bytes.Equal(value, digest[:]) {
+			return errDigestMismatch
+		}
+
+		return nil
+	}
+
+	return errDigestMismatch
+}
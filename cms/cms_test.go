@@ -0,0 +1,113 @@
+package cms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+)
+
+// createTestCertificate returns a self-signed certificate for a freshly generated key.
+func createTestCertificate(t *testing.T, key crypto.Signer, serial int64) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "sap test announcer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed with error: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed with error: %v", err)
+	}
+
+	return cert
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed with error: %v", err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed with error: %v", err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{name: "RSA", key: rsaKey},
+		{name: "ECDSA", key: ecdsaKey},
+		{name: "Ed25519", key: ed25519Key},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cert := createTestCertificate(t, tc.key, int64(i+1))
+			other := createTestCertificate(t, tc.key, int64(i+100))
+
+			signer, err := NewSigner(cert, tc.key)
+			if err != nil {
+				t.Fatalf("NewSigner failed with error: %v", err)
+			}
+
+			p, err := sap.NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, sap.WithMessageType(sap.Deletion), sap.WithSigner(signer))
+			if err != nil {
+				t.Fatalf("NewPacket failed with error: %v", err)
+			}
+
+			data, err := p.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			p2 := &sap.Packet{}
+			if err := p2.Unmarshal(data, sap.WithVerifiers(NewVerifier(other, cert))); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			if err := p2.Unmarshal(data, sap.WithVerifiers(NewVerifier(other))); !errors.Is(err, errUntrustedSigner) {
+				t.Errorf("Expected error %v, but got %v", errUntrustedSigner, err)
+			}
+
+			// Forge the message type of the signed packet
+			forged := append([]byte{}, data...)
+			forged[0] &^= 0x04
+			if err := p2.Unmarshal(forged, sap.WithVerifiers(NewVerifier(cert))); err == nil {
+				t.Error("Expected a forged packet to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	v := NewVerifier()
+
+	if err := v.Verify([]byte("data"), []byte{0x30, 0x00}); err != errNotSignedData {
+		t.Errorf("Expected error %v, but got %v", errNotSignedData, err)
+	}
+}
@@ -0,0 +1,81 @@
+package sap
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec decodes the payloads of the payload types it is registered for
+type Codec interface {
+	Decode(payload []byte) (any, error)
+}
+
+// CodecFunc is a function used as a Codec
+type CodecFunc func(payload []byte) (any, error)
+
+// Decode calls f(payload)
+func (f CodecFunc) Decode(payload []byte) (any, error) {
+	return f(payload)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		SDPPayloadType: CodecFunc(decodeSDP),
+	}
+)
+
+// RegisterCodec registers the codec for a media type such as application/x-aes67-json, replacing any codec
+// already registered for it. Parameters and case are ignored. A codec decoding application/sdp payloads into
+// a *sdp.SessionDescription is registered by default.
+func RegisterCodec(payloadType string, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[normalizeMediaType(payloadType)] = c
+}
+
+// LookupCodec returns the codec registered for the payload type
+func LookupCodec(payloadType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[normalizeMediaType(payloadType)]
+	return c, ok
+}
+
+// DecodePayload decodes the payload with the codec registered for the payload type of the packet,
+// application/sdp when it was omitted. It returns ErrNoCodec if no codec is registered for it,
+// and a *PayloadTypeError if the packet could not be decrypted.
+func (p Packet) DecodePayload() (any, error) {
+	if p.Undecryptable() {
+		return nil, &PayloadTypeError{}
+	}
+
+	payloadType := p.PayloadType
+	if payloadType == "" {
+		payloadType = SDPPayloadType
+	}
+
+	c, ok := LookupCodec(payloadType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoCodec, payloadType)
+	}
+
+	return c.Decode(p.Payload)
+}
+
+// normalizeMediaType returns the media type without parameters, in lower case
+func normalizeMediaType(payloadType string) string {
+	if mediaType, _, err := mime.ParseMediaType(payloadType); err == nil {
+		return mediaType
+	}
+
+	return strings.ToLower(strings.TrimSpace(payloadType))
+}
+
+func decodeSDP(payload []byte) (any, error) {
+	return Packet{Payload: payload}.SessionDescription()
+}
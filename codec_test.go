@@ -0,0 +1,71 @@
+package sap
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pion/sdp/v3"
+)
+
+type aes67Stream struct {
+	Name string `json:"name"`
+}
+
+func TestDecodePayload(t *testing.T) {
+	RegisterCodec("application/x-aes67-json", CodecFunc(func(payload []byte) (any, error) {
+		stream := aes67Stream{}
+		err := json.Unmarshal(payload, &stream)
+		return stream, err
+	}))
+
+	payload, err := createMockSessionDescription().Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		mockPacket    Packet
+		expected      any
+		expectedError error
+	}{
+		{
+			name:       "ImplicitSDP",
+			mockPacket: Packet{Payload: payload},
+			expected:   "Session",
+		},
+		{
+			name:       "SDPWithByteOrderMark",
+			mockPacket: Packet{Header: Header{PayloadTypeSource: PayloadTypeQuirk}, Payload: append([]byte("\xEF\xBB\xBF"), payload...)},
+			expected:   "Session",
+		},
+		{
+			name:       "RegisteredWithParameters",
+			mockPacket: Packet{Header: Header{PayloadType: "Application/X-AES67-JSON; charset=utf-8"}, Payload: []byte(`{"name":"Stream"}`)},
+			expected:   aes67Stream{Name: "Stream"},
+		},
+		{
+			name:          "NotRegistered",
+			mockPacket:    Packet{Header: Header{PayloadType: "application/sdpng+xml"}, Payload: []byte("<sdpng/>")},
+			expectedError: ErrNoCodec,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := tc.mockPacket.DecodePayload()
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+
+			if desc, ok := v.(*sdp.SessionDescription); ok {
+				v = string(desc.SessionName)
+			}
+
+			if err == nil && v != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, v)
+			}
+		})
+	}
+}
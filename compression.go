@@ -0,0 +1,50 @@
+package sap
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+)
+
+// maxDecompressedSize is the largest size a compressed payload may inflate to.
+// A SAP packet fits in a single UDP datagram, so anything inflating past 64 KiB
+// is either broken or an attempt to exhaust the listener's memory.
+const maxDecompressedSize = 64 * 1024
+
+// compress deflates data using the zlib format (RFC 1950).
+func compress(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// decompress inflates zlib compressed data.
+// It fails with ErrDecompressedTooLarge if the output would exceed maxDecompressedSize.
+func decompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidCompressedPayload
+	}
+	defer r.Close()
+
+	// Read one byte past the limit so an oversized payload can be told apart
+	// from one that is exactly maxDecompressedSize long
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, ErrInvalidCompressedPayload
+	}
+
+	if len(out) > maxDecompressedSize {
+		return nil, ErrDecompressedTooLarge
+	}
+
+	return out, nil
+}
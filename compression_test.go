@@ -0,0 +1,119 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestPacketCompressedMarshalAndUnmarshal checks that a compressed packet can be marshaled and then unmarshaled to its original state.
+func TestPacketCompressedMarshalAndUnmarshal(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mockPacket *Packet
+	}{
+		{
+			name: "Test 1: Implicit Payload Type",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Compressed: 1, PayloadTypeSource: PayloadTypeImplicit},
+				Payload: []byte("v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=Test\r\n"),
+			}),
+		},
+		{
+			name: "Test 2: Explicit Payload Type",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Compressed: 1, PayloadType: "application/sdp", PayloadTypeSource: PayloadTypeExplicit},
+				Payload: []byte("v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=Test\r\n"),
+			}),
+		},
+		{
+			name: "Test 3: IPv6 and AuthenticationLength = 2",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Compressed: 1, AddressType: 1, AuthenticationLength: 2, PayloadType: "application/json", PayloadTypeSource: PayloadTypeExplicit},
+				Payload: []byte(`{"session":"test"}`),
+			}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.mockPacket.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			p2 := CreateMockPacket(*tc.mockPacket)
+			err = p2.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.mockPacket, p2) {
+				t.Errorf("original and unmarshalled packets do not match\n%v\n%v", tc.mockPacket, p2)
+			}
+		})
+	}
+}
+
+func TestPacketCompressedUnmarshalErrors(t *testing.T) {
+	header := CreateMockHeader(Header{Compressed: 1})
+	headerBytes, err := header.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	bomb, err := compress(make([]byte, maxDecompressedSize+1))
+	if err != nil {
+		t.Fatalf("compress failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		body          []byte
+		expectedError error
+	}{
+		{
+			name:          "InvalidCompressedPayload",
+			body:          []byte("v=0\r\n"),
+			expectedError: ErrInvalidCompressedPayload,
+		},
+		{
+			name:          "DecompressedTooLarge",
+			body:          bomb,
+			expectedError: ErrDecompressedTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Packet{}
+			err := p.Unmarshal(append(append([]byte{}, headerBytes...), tc.body...))
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNewPacketWithCompression(t *testing.T) {
+	payload := []byte("v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=Test\r\n")
+
+	p, err := NewPacket(payload, net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, WithCompression())
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	if p.Compressed != 1 {
+		t.Errorf("expected the compressed bit to be set")
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	if len(data) != p.MarshalSize() {
+		t.Errorf("expected %d bytes, got %d", p.MarshalSize(), len(data))
+	}
+}
@@ -0,0 +1,194 @@
+package sap
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// MinSessionTimeout is the shortest time a session is kept after its last announcement (RFC 2974 section 3.2)
+const MinSessionTimeout = time.Hour
+
+// SessionTimeout returns how long a session is kept after its last announcement,
+// given the interval observed between its announcements.
+// That is the larger of one hour and ten times the interval (RFC 2974 section 3.2).
+func SessionTimeout(interval time.Duration) time.Duration {
+	if timeout := 10 * interval; timeout > MinSessionTimeout {
+		return timeout
+	}
+
+	return MinSessionTimeout
+}
+
+// AnnouncementKey identifies a version of an announced session,
+// by its originating source and message id hash.
+// It is comparable and can be used as a map key.
+type AnnouncementKey struct {
+	Source        netip.Addr
+	MessageIDHash uint16
+}
+
+// AnnouncementKey returns the key identifying the announcement the header belongs to.
+func (h Header) AnnouncementKey() AnnouncementKey {
+	return AnnouncementKey{Source: h.Source(), MessageIDHash: h.MessageIDHash}
+}
+
+// Entry is a session known to a Directory.
+type Entry struct {
+	// The last announcement received for the session
+	Packet *Packet
+
+	// When the session was first and last announced
+	FirstSeen time.Time
+	LastSeen  time.Time
+
+	// The time between the last two announcements, zero if the session was announced once
+	Interval time.Duration
+
+	// The SDP origin of the session, see sessionOrigin
+	origin string
+}
+
+// Expires returns the time at which the session times out unless it is announced again.
+func (e Entry) Expires() time.Time {
+	return e.LastSeen.Add(SessionTimeout(e.Interval))
+}
+
+// Directory is a cache of the sessions announced in one or more scopes.
+// Sessions are added when announced, removed when a deletion is received
+// and expire when they are no longer announced.
+//
+// A new version of a session, announced by the same source with the same SDP origin
+// but a different message id hash, replaces the previous one.
+// A deletion only removes the version with its source and message id hash.
+type Directory struct {
+	mu      sync.Mutex
+	entries map[AnnouncementKey]*Entry
+
+	subscribers    map[int]func(Event)
+	nextSubscriber int
+	emitMu         sync.Mutex
+}
+
+// NewDirectory returns an empty Directory.
+func NewDirectory() *Directory {
+	return &Directory{
+		entries:     map[AnnouncementKey]*Entry{},
+		subscribers: map[int]func(Event){},
+	}
+}
+
+// Handle updates the directory with a packet received at now.
+// Announcements add, refresh or modify their session, deletions remove it.
+func (d *Directory) Handle(p *Packet, now time.Time) {
+	key := p.AnnouncementKey()
+	origin := sessionOrigin(p)
+
+	d.mu.Lock()
+	d.emit(d.handle(p, key, origin, now))
+}
+
+// handle updates the directory and returns the resulting events. d.mu must be held.
+func (d *Directory) handle(p *Packet, key AnnouncementKey, origin string, now time.Time) []Event {
+	if p.MessageType == Deletion {
+		// Deletions of a previous version, received after the current one, are ignored
+		entry, ok := d.entries[key]
+		if !ok {
+			return nil
+		}
+
+		delete(d.entries, key)
+		return []Event{{Type: SessionDeleted, Packet: p, Previous: entry.Packet}}
+	}
+
+	if entry, ok := d.entries[key]; ok {
+		entry.Packet = p
+		entry.Interval = now.Sub(entry.LastSeen)
+		entry.LastSeen = now
+		return nil
+	}
+
+	if previousKey, entry, ok := d.findOrigin(key.Source, origin); ok {
+		previous := entry.Packet
+
+		delete(d.entries, previousKey)
+		d.entries[key] = &Entry{
+			Packet:    p,
+			FirstSeen: entry.FirstSeen,
+			LastSeen:  now,
+			Interval:  now.Sub(entry.LastSeen),
+			origin:    origin,
+		}
+
+		return []Event{{Type: SessionModified, Packet: p, Previous: previous}}
+	}
+
+	d.entries[key] = &Entry{
+		Packet:    p,
+		FirstSeen: now,
+		LastSeen:  now,
+		origin:    origin,
+	}
+
+	return []Event{{Type: SessionAdded, Packet: p}}
+}
+
+// findOrigin returns the session announced by source with the given SDP origin. d.mu must be held.
+func (d *Directory) findOrigin(source netip.Addr, origin string) (AnnouncementKey, *Entry, bool) {
+	if origin == "" {
+		return AnnouncementKey{}, nil, false
+	}
+
+	for key, entry := range d.entries {
+		if key.Source == source && entry.origin == origin {
+			return key, entry, true
+		}
+	}
+
+	return AnnouncementKey{}, nil, false
+}
+
+// Expire removes the sessions that have timed out at now and returns them.
+func (d *Directory) Expire(now time.Time) []Entry {
+	d.mu.Lock()
+
+	var expired []Entry
+	var events []Event
+	for key, entry := range d.entries {
+		if now.After(entry.Expires()) {
+			expired = append(expired, *entry)
+			events = append(events, Event{Type: SessionExpired, Packet: entry.Packet})
+			delete(d.entries, key)
+		}
+	}
+
+	d.emit(events)
+
+	return expired
+}
+
+// Lookup returns the session identified by key.
+func (d *Directory) Lookup(key AnnouncementKey) (Entry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+
+	return *entry, true
+}
+
+// Sessions returns all the sessions in the directory, in no particular order.
+func (d *Directory) Sessions() []Entry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make([]Entry, 0, len(d.entries))
+	for _, entry := range d.entries {
+		entries = append(entries, *entry)
+	}
+
+	return entries
+}
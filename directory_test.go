@@ -0,0 +1,83 @@
+package sap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionTimeout(t *testing.T) {
+	testCases := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{
+			name:     "Test 1: Announced Once",
+			interval: 0,
+			want:     time.Hour,
+		},
+		{
+			name:     "Test 2: Minimum Interval",
+			interval: MinAnnouncementInterval,
+			want:     time.Hour,
+		},
+		{
+			name:     "Test 3: Long Interval",
+			interval: 10 * time.Minute,
+			want:     100 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SessionTimeout(tc.interval)
+			if tc.want != got {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	now := time.Now()
+	d := NewDirectory()
+
+	first := CreateMockPacket(Packet{Header: Header{MessageIDHash: 1}, Payload: []byte("v=0\r\n")})
+	second := CreateMockPacket(Packet{Header: Header{MessageIDHash: 2}, Payload: []byte("v=0\r\n")})
+
+	d.Handle(first, now)
+	d.Handle(second, now)
+	d.Handle(first, now.Add(10*time.Minute))
+
+	if got := len(d.Sessions()); got != 2 {
+		t.Fatalf("expected %d sessions, got %d", 2, got)
+	}
+
+	entry, ok := d.Lookup(first.AnnouncementKey())
+	if !ok {
+		t.Fatal("expected the first session to be in the directory")
+	}
+	if !entry.FirstSeen.Equal(now) || !entry.LastSeen.Equal(now.Add(10*time.Minute)) {
+		t.Errorf("unexpected first and last seen times %v and %v", entry.FirstSeen, entry.LastSeen)
+	}
+	if entry.Interval != 10*time.Minute {
+		t.Errorf("expected interval %v, got %v", 10*time.Minute, entry.Interval)
+	}
+
+	// The second session times out an hour after it was announced, the first one 100 minutes after its last announcement
+	expired := d.Expire(now.Add(time.Hour + time.Second))
+	if len(expired) != 1 || expired[0].Packet != second {
+		t.Fatalf("expected the second session to expire, got %v", expired)
+	}
+
+	if _, ok := d.Lookup(first.AnnouncementKey()); !ok {
+		t.Fatal("expected the first session to be in the directory")
+	}
+
+	deletion := CreateMockPacket(Packet{Header: Header{MessageIDHash: 1, MessageType: Deletion}})
+	d.Handle(deletion, now.Add(20*time.Minute))
+
+	if got := len(d.Sessions()); got != 0 {
+		t.Errorf("expected %d sessions, got %d", 0, got)
+	}
+}
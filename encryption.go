@@ -0,0 +1,240 @@
+package sap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+// Cipher encrypts and decrypts SAP payloads using a single key.
+type Cipher interface {
+	// BlockSize returns the size the plaintext must be a multiple of.
+	// Stream ciphers return 1, in which case no padding is added.
+	BlockSize() int
+
+	// Encrypt returns the ciphertext of plaintext, whose length is a multiple of BlockSize.
+	Encrypt(plaintext []byte) ([]byte, error)
+
+	// Decrypt returns the plaintext of ciphertext.
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// Keyring gives access to the ciphers of the keys held by a listener.
+type Keyring interface {
+	// Cipher returns the Cipher for keyID, or false if the key is not held.
+	Cipher(keyID uint32) (Cipher, bool)
+}
+
+// StaticKeyring is a Keyring backed by a map of key ids to ciphers.
+type StaticKeyring map[uint32]Cipher
+
+// Cipher returns the Cipher for keyID, or false if the key is not held.
+func (k StaticKeyring) Cipher(keyID uint32) (Cipher, bool) {
+	c, ok := k[keyID]
+	return c, ok
+}
+
+// Encryption holds the fields sent in front of an encrypted payload.
+type Encryption struct {
+	/*
+	    0                   1                   2                   3
+	    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	   |                         key id                                |
+	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	   |                           timeout                             |
+	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	   |P|                    random field                             |
+	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	   |                       encrypted payload                       |
+	   :                                                               :
+	*/
+
+	// Identifies the key used to encrypt the payload.
+	KeyID uint32
+
+	// The time at which the session should be timed out. Listeners that cannot
+	// decrypt the payload have no access to its timing, so it is sent in the clear.
+	// The zero value means no timeout is sent.
+	Timeout time.Time
+
+	// If the padding bit is set to 1, the last octet of the decrypted payload
+	// gives the number of padding octets, including itself, to be ignored.
+	// It is set on received packets, Packet.Marshal pads whenever the Cipher requires it.
+	Padding uint8
+
+	// The Cipher used to encrypt the payload, or the one that decrypted it.
+	// It is nil when a received packet was encrypted with a key that is not held.
+	Cipher Cipher
+}
+
+const (
+	encryptionHeaderSize = 12 // key id, timeout and the padding bit with the random field
+	paddingShift         = 31 // Number of bits to shift for the padding bit
+	randomFieldMask      = 0x7FFFFFFF
+
+	// Seconds between the NTP epoch (1900) and the Unix epoch (1970)
+	ntpEpochOffset = 2208988800
+)
+
+// encrypt pads data to the block size of the Cipher, encrypts it
+// and prepends the key id, timeout and random field.
+func (e *Encryption) encrypt(data []byte) ([]byte, error) {
+	if e == nil || e.Cipher == nil {
+		return nil, errNoCipher
+	}
+
+	var padding uint8
+	if blockSize := e.Cipher.BlockSize(); blockSize > 1 {
+		// The padding octet count is part of the padding, so there is always at least one
+		padLen := blockSize - len(data)%blockSize
+		if padLen > 0xFF {
+			return nil, ErrInvalidPadding
+		}
+
+		padded := make([]byte, len(data)+padLen)
+		copy(padded, data)
+		padded[len(padded)-1] = byte(padLen)
+
+		data = padded
+		padding = 1
+	}
+
+	ciphertext, err := e.Cipher.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var random [4]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, encryptionHeaderSize+len(ciphertext))
+	binary.BigEndian.PutUint32(buf[0:4], e.KeyID)
+	binary.BigEndian.PutUint32(buf[4:8], toNTPSeconds(e.Timeout))
+	binary.BigEndian.PutUint32(buf[8:12], uint32(padding)<<paddingShift|binary.BigEndian.Uint32(random[:])&randomFieldMask)
+	copy(buf[encryptionHeaderSize:], ciphertext)
+
+	return buf, nil
+}
+
+// unmarshalEncryption parses the fields in front of an encrypted payload.
+// It returns them along with the ciphertext.
+func unmarshalEncryption(buf []byte) (*Encryption, []byte, error) {
+	if len(buf) < encryptionHeaderSize {
+		return nil, nil, ErrBufTooSmallForEncryption
+	}
+
+	e := &Encryption{
+		KeyID:   binary.BigEndian.Uint32(buf[0:4]),
+		Timeout: fromNTPSeconds(binary.BigEndian.Uint32(buf[4:8])),
+		Padding: uint8(binary.BigEndian.Uint32(buf[8:12]) >> paddingShift),
+	}
+
+	return e, buf[encryptionHeaderSize:], nil
+}
+
+// decrypt decrypts ciphertext with the Cipher and strips any padding.
+func (e *Encryption) decrypt(ciphertext []byte) ([]byte, error) {
+	plaintext, err := e.Cipher.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Padding == 0 {
+		return plaintext, nil
+	}
+
+	if len(plaintext) == 0 {
+		return nil, ErrInvalidPadding
+	}
+
+	padLen := int(plaintext[len(plaintext)-1])
+	if padLen == 0 || padLen > len(plaintext) {
+		return nil, ErrInvalidPadding
+	}
+
+	return plaintext[:len(plaintext)-padLen], nil
+}
+
+// toNTPSeconds converts t to the 32 bit seconds of an NTP timestamp.
+func toNTPSeconds(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+
+	// Truncating to 32 bits wraps into the next NTP era after 2036
+	return uint32(t.Unix() + ntpEpochOffset)
+}
+
+// fromNTPSeconds converts the 32 bit seconds of an NTP timestamp to a time.
+func fromNTPSeconds(seconds uint32) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+
+	// As in RFC 4330, values with the most significant bit cleared are taken to be in
+	// the era starting in 2036 since SAP predates 1968
+	secs := int64(seconds)
+	if seconds&(1<<31) == 0 {
+		secs += 1 << 32
+	}
+
+	return time.Unix(secs-ntpEpochOffset, 0).UTC()
+}
+
+// aesCipher is a Cipher using AES in CBC mode.
+type aesCipher struct {
+	block cipher.Block
+}
+
+// NewAESCipher returns a Cipher using AES in CBC mode.
+// The key must be 16, 24 or 32 bytes long. A random IV is
+// generated for each payload and sent in front of the ciphertext.
+func NewAESCipher(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesCipher{block: block}, nil
+}
+
+// BlockSize returns the AES block size.
+func (c *aesCipher) BlockSize() int {
+	return c.block.BlockSize()
+}
+
+// Encrypt encrypts plaintext and prepends the IV.
+func (c *aesCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	blockSize := c.block.BlockSize()
+	if len(plaintext)%blockSize != 0 {
+		return nil, ErrInvalidCiphertext
+	}
+
+	buf := make([]byte, blockSize+len(plaintext))
+	iv := buf[:blockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	cipher.NewCBCEncrypter(c.block, iv).CryptBlocks(buf[blockSize:], plaintext)
+
+	return buf, nil
+}
+
+// Decrypt reads the IV in front of ciphertext and decrypts the rest.
+func (c *aesCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	blockSize := c.block.BlockSize()
+	if len(ciphertext) < blockSize || len(ciphertext)%blockSize != 0 {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext := make([]byte, len(ciphertext)-blockSize)
+	cipher.NewCBCDecrypter(c.block, ciphertext[:blockSize]).CryptBlocks(plaintext, ciphertext[blockSize:])
+
+	return plaintext, nil
+}
@@ -0,0 +1,206 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestPacketEncryptedMarshalAndUnmarshal checks that an encrypted packet can be marshaled and then decrypted to its original state.
+func TestPacketEncryptedMarshalAndUnmarshal(t *testing.T) {
+	c, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("NewAESCipher failed with error: %v", err)
+	}
+
+	payload := []byte("v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=Private\r\n")
+
+	testCases := []struct {
+		name       string
+		mockPacket *Packet
+	}{
+		{
+			name: "Test 1: Implicit Payload Type",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Encrypted: 1, PayloadTypeSource: PayloadTypeImplicit},
+				Payload: payload,
+			}),
+		},
+		{
+			name: "Test 2: Explicit Payload Type",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Encrypted: 1, PayloadType: "application/sdp", PayloadTypeSource: PayloadTypeExplicit},
+				Payload: payload,
+			}),
+		},
+		{
+			name: "Test 3: Compressed",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Encrypted: 1, Compressed: 1, AddressType: 1, PayloadTypeSource: PayloadTypeImplicit},
+				Payload: payload,
+			}),
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mockPacket.Encryption = &Encryption{
+				KeyID:   uint32(i + 1),
+				Timeout: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+				Padding: 1,
+				Cipher:  c,
+			}
+
+			data, err := tc.mockPacket.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			p2 := CreateMockPacket(*tc.mockPacket)
+			err = p2.Unmarshal(data, WithKeyring(StaticKeyring{uint32(i + 1): c}))
+			if err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			if p2.Undecryptable() {
+				t.Error("expected the packet to be decrypted")
+			}
+
+			if !reflect.DeepEqual(tc.mockPacket, p2) {
+				t.Errorf("original and unmarshalled packets do not match\n%v\n%v", tc.mockPacket, p2)
+			}
+		})
+	}
+}
+
+// TestPacketUndecryptable checks that a packet encrypted with an unknown key is decoded without error.
+func TestPacketUndecryptable(t *testing.T) {
+	c, err := NewAESCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewAESCipher failed with error: %v", err)
+	}
+
+	p, err := NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, WithEncryption(7, time.Time{}, c))
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		opts []UnmarshalOption
+	}{
+		{
+			name: "No Keyring",
+		},
+		{
+			name: "Unknown Key",
+			opts: []UnmarshalOption{WithKeyring(StaticKeyring{8: c})},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p2 := &Packet{}
+			err = p2.Unmarshal(data, tc.opts...)
+			if err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			if !p2.Undecryptable() {
+				t.Error("expected the packet to be undecryptable")
+			}
+
+			if p2.Encryption.KeyID != 7 {
+				t.Errorf("expected key id 7, got %d", p2.Encryption.KeyID)
+			}
+
+			if p2.PayloadType != "" {
+				t.Errorf("expected no payload type, got %s", p2.PayloadType)
+			}
+		})
+	}
+}
+
+func TestPacketEncryptedErrors(t *testing.T) {
+	c, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("NewAESCipher failed with error: %v", err)
+	}
+
+	p := CreateMockPacket(Packet{Header: Header{Encrypted: 1}, Payload: []byte("v=0\r\n")})
+	if _, err := p.Marshal(); err != errNoCipher {
+		t.Errorf("Expected error %v, but got %v", errNoCipher, err)
+	}
+
+	header := CreateMockHeader(Header{Encrypted: 1})
+	headerBytes, err := header.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		body          []byte
+		expectedError error
+	}{
+		{
+			name:          "BufTooSmallForEncryption",
+			body:          []byte{0, 0, 0, 1, 0, 0, 0, 0},
+			expectedError: ErrBufTooSmallForEncryption,
+		},
+		{
+			name:          "InvalidCiphertext",
+			body:          []byte{0, 0, 0, 1, 0, 0, 0, 0, 0x80, 0, 0, 0, 1, 2, 3},
+			expectedError: ErrInvalidCiphertext,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Packet{}
+			err := p.Unmarshal(append(append([]byte{}, headerBytes...), tc.body...), WithKeyring(StaticKeyring{1: c}))
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNTPSeconds(t *testing.T) {
+	for _, want := range []time.Time{
+		{},
+		time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2040, 6, 1, 12, 0, 0, 0, time.UTC),
+	} {
+		if got := fromNTPSeconds(toNTPSeconds(want)); !got.Equal(want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestNewPacketWithHeaderOption(t *testing.T) {
+	c, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("NewAESCipher failed with error: %v", err)
+	}
+
+	withReservedBit := Option(func(h *Header) {
+		h.Reserved = 1
+	})
+
+	p, err := NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, withReservedBit, WithEncryption(7, time.Time{}, c))
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	if p.Reserved != 1 || p.Encryption == nil || p.Encryption.KeyID != 7 {
+		t.Errorf("unexpected reserved bit %d and encryption %v", p.Reserved, p.Encryption)
+	}
+}
@@ -2,17 +2,117 @@ package sap
 
 import (
 	"errors"
+	"fmt"
 )
 
+// Errors returned when decoding a packet, wrapped in a ParseError, or by Header.Validate.
+// Use errors.Is to tell them apart.
 var (
-	errBufTooSmallForFlags      = errors.New("buffer too small for Flags")
-	errBufTooSmallForAuthLength = errors.New("buffer too small for Authentication Length")
-	errBufTooSmallForAuthData   = errors.New("buffer too small for Authentication Data")
-	errBufTooSmallForMsgIdHash  = errors.New("buffer too small for message id hash")
-	errBufTooSmallForIPv4       = errors.New("buffer too small for IPv4 address")
-	errBufTooSmallForIPv6       = errors.New("buffer too small for IPv6 address")
-	errBufTooSmallForPayload    = errors.New("buffer too small for the payload")
-	errBufTooSmallForHeader     = errors.New("buffer too small for the header")
-	errNoTrailingByteFound      = errors.New("didn't find the trailing byte from the buffer")
-	errInvalidIPOnHeader        = errors.New("invalid IP in the OriginatingSource field on the Header Struct")
+	ErrBufTooSmallForFlags      = errors.New("buffer too small for Flags")
+	ErrBufTooSmallForAuthLength = errors.New("buffer too small for Authentication Length")
+	ErrBufTooSmallForAuthData   = errors.New("buffer too small for Authentication Data")
+	ErrBufTooSmallForMsgIdHash  = errors.New("buffer too small for message id hash")
+	ErrBufTooSmallForIPv4       = errors.New("buffer too small for IPv4 address")
+	ErrBufTooSmallForIPv6       = errors.New("buffer too small for IPv6 address")
+	ErrNoTrailingByteFound      = errors.New("didn't find the trailing byte from the buffer")
+	ErrInvalidPayloadType       = errors.New("payload type is not a valid media type")
+	ErrUnknownPayloadType       = errors.New("payload type is not a known media type")
+	ErrInvalidCompressedPayload = errors.New("payload is not valid zlib compressed data")
+	ErrDecompressedTooLarge     = errors.New("decompressed payload exceeds the maximum allowed size")
+	ErrBufTooSmallForEncryption = errors.New("buffer too small for the key id, timeout and random field of an encrypted payload")
+	ErrInvalidPadding           = errors.New("invalid padding in the decrypted payload")
+	ErrInvalidCiphertext        = errors.New("ciphertext is not a multiple of the block size")
+	ErrNoAuthHeader             = errors.New("no authentication data to parse the authentication header from")
+	ErrInvalidAuthPadding       = errors.New("invalid padding in the authentication header")
+	ErrNotAuthenticated         = errors.New("packet carries no authentication data")
+	ErrNoVerifierForAuthType    = errors.New("no verifier for the authentication type of the packet")
+	ErrInvalidSignature         = errors.New("invalid signature")
+	ErrInvalidVersion           = errors.New("version number is not 1")
+	ErrReservedBitSet           = errors.New("reserved bit is set")
+	ErrInvalidFlag              = errors.New("one bit field holds a value other than 0 or 1")
+	ErrAuthLengthMismatch       = errors.New("authentication length does not match the authentication data")
+	ErrZeroMessageIDHash        = errors.New("message id hash is zero")
+	ErrAddressTypeMismatch      = errors.New("address type does not match the originating source")
+	ErrLegacyIPv6               = errors.New("SAPv0 packets only have IPv4 originating sources")
+	ErrLegacyVersion            = errors.New("SAPv0 packets are not accepted")
 )
+
+// ErrNoCodec is returned by Packet.DecodePayload when no codec is registered for the payload type
+var ErrNoCodec = errors.New("no codec registered for the payload type")
+
+var (
+	errBufTooSmallForPayload = errors.New("buffer too small for the payload")
+	errBufTooSmallForHeader  = errors.New("buffer too small for the header")
+	errInvalidIPOnHeader     = errors.New("invalid IP in the OriginatingSource field on the Header Struct")
+	errNoCipher              = errors.New("encrypted bit is set but no Cipher was provided")
+	errAuthDataTooLarge      = errors.New("authentication data exceeds 255 32 bit words")
+	errAnnouncerClosed       = errors.New("announcer is closed")
+	errUnknownSession        = errors.New("session is not announced by this announcer")
+	errHashesExhausted       = errors.New("every message id hash is used by a session of the source")
+	errNotMulticast          = errors.New("session address is not a multicast address")
+	errReservedScope         = errors.New("session address has a reserved IPv6 multicast scope")
+	errIncompleteSDP         = errors.New("session description has no session name or timing")
+)
+
+// ParseError describes why a packet could not be decoded.
+type ParseError struct {
+	// The field being decoded, as named in RFC 2974
+	Field string
+
+	// The position of the field in the packet
+	Offset int
+
+	// The number of bytes the field needs and the number of bytes left in the packet,
+	// both zero when the field is malformed rather than truncated
+	Expected  int
+	Available int
+
+	// The reason of the failure, one of the exported sentinel errors, possibly wrapping another error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Expected > 0 {
+		return fmt.Sprintf("%v: %s at offset %d needs %d bytes, %d available", e.Err, e.Field, e.Offset, e.Expected, e.Available)
+	}
+
+	return fmt.Sprintf("%v: %s at offset %d", e.Err, e.Field, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// truncated returns the error for a field of expected bytes at offset, when buf is too short to hold it.
+func truncated(err error, field string, buf []byte, offset, expected int) *ParseError {
+	return &ParseError{
+		Field:     field,
+		Offset:    offset,
+		Expected:  expected,
+		Available: len(buf) - offset,
+		Err:       err,
+	}
+}
+
+// malformed returns the error for a field at offset holding an invalid value.
+func malformed(err error, field string, offset int) *ParseError {
+	return &ParseError{
+		Field:  field,
+		Offset: offset,
+		Err:    err,
+	}
+}
+
+// PayloadTypeError is returned when the payload of a packet is not of the expected type.
+type PayloadTypeError struct {
+	// The payload type of the packet, empty if it is unknown because the packet could not be decrypted
+	PayloadType string
+}
+
+func (e *PayloadTypeError) Error() string {
+	if e.PayloadType == "" {
+		return "payload type of an undecryptable packet is unknown"
+	}
+
+	return fmt.Sprintf("payload type %q is not %s", e.PayloadType, SDPPayloadType)
+}
@@ -0,0 +1,136 @@
+package sap
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// The kind of change to a session reported by an Event
+type EventType uint8
+
+const (
+	// A session was announced for the first time
+	SessionAdded EventType = iota
+
+	// A new version of a known session was announced
+	SessionModified
+
+	// A deletion packet was received for a session
+	SessionDeleted
+
+	// A session timed out as it was no longer announced
+	SessionExpired
+)
+
+func (t EventType) String() string {
+	switch t {
+	case SessionAdded:
+		return "added"
+	case SessionModified:
+		return "modified"
+	case SessionDeleted:
+		return "deleted"
+	case SessionExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// Event reports a change to a session of a Directory.
+type Event struct {
+	Type EventType
+
+	// The packet that caused the event: the new announcement for SessionAdded and SessionModified,
+	// the deletion packet for SessionDeleted and the last announcement for SessionExpired
+	Packet *Packet
+
+	// The announcement replaced or removed by the event, nil for SessionAdded and SessionExpired
+	Previous *Packet
+}
+
+// Subscribe calls handler with every change to the sessions of the directory, in the order they happen.
+// The handler is called from the goroutine updating the directory and may read it, but must not block for long.
+// The returned function cancels the subscription.
+func (d *Directory) Subscribe(handler func(Event)) (unsubscribe func()) {
+	_, unsubscribe = d.subscribe(handler, false)
+
+	return unsubscribe
+}
+
+// subscribe adds handler to the subscribers. With withPackets, it also returns the packets of the sessions
+// taken under the same lock, so that the events the handler receives apply after them.
+func (d *Directory) subscribe(handler func(Event), withPackets bool) (packets []*Packet, unsubscribe func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id := d.nextSubscriber
+	d.nextSubscriber++
+	d.subscribers[id] = handler
+
+	if withPackets {
+		packets = make([]*Packet, 0, len(d.entries))
+		for _, entry := range d.entries {
+			packets = append(packets, entry.Packet)
+		}
+	}
+
+	return packets, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		delete(d.subscribers, id)
+	}
+}
+
+// emit delivers events to the subscribers. d.mu must be held, it is released before calling the handlers.
+func (d *Directory) emit(events []Event) {
+	if len(events) == 0 || len(d.subscribers) == 0 {
+		d.mu.Unlock()
+		return
+	}
+
+	handlers := make([]func(Event), 0, len(d.subscribers))
+	for _, handler := range d.subscribers {
+		handlers = append(handlers, handler)
+	}
+
+	// Hold the emit lock before releasing the directory, so events are delivered in order
+	d.emitMu.Lock()
+	defer d.emitMu.Unlock()
+	d.mu.Unlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
+
+// sessionOrigin returns the origin (o=) line of the SDP description carried by the packet,
+// without the session version, or "" if there is none.
+// It identifies the session across the versions of its description (RFC 4566 section 5.2).
+func sessionOrigin(p *Packet) string {
+	if p.Undecryptable() || (p.PayloadType != "" && p.PayloadType != "application/sdp") {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(p.Payload))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if !strings.HasPrefix(line, "o=") {
+			continue
+		}
+
+		// o=<username> <sess-id> <sess-version> <nettype> <addrtype> <unicast-address>
+		fields := strings.Fields(line[2:])
+		if len(fields) != 6 {
+			return ""
+		}
+
+		return strings.Join(append(fields[:2:2], fields[3:]...), " ")
+	}
+
+	return ""
+}
@@ -0,0 +1,140 @@
+package sap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionOrigin(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mockPacket *Packet
+		want       string
+	}{
+		{
+			name:       "Test 1: SDP Origin",
+			mockPacket: CreateMockPacket(Packet{Payload: []byte("v=0\r\no=- 1234 5 IN IP4 192.0.2.1\r\ns=Session\r\n")}),
+			want:       "- 1234 IN IP4 192.0.2.1",
+		},
+		{
+			name:       "Test 2: No Origin",
+			mockPacket: CreateMockPacket(Packet{Payload: []byte("v=0\r\ns=Session\r\n")}),
+			want:       "",
+		},
+		{
+			name:       "Test 3: Malformed Origin",
+			mockPacket: CreateMockPacket(Packet{Payload: []byte("v=0\r\no=- 1234\r\n")}),
+			want:       "",
+		},
+		{
+			name: "Test 4: Other Payload Type",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{PayloadType: "text/plain"},
+				Payload: []byte("o=- 1234 5 IN IP4 192.0.2.1\r\n"),
+			}),
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := sessionOrigin(tc.mockPacket)
+			if tc.want != got {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDirectoryEvents(t *testing.T) {
+	now := time.Now()
+	d := NewDirectory()
+
+	var events []Event
+	unsubscribe := d.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	v1 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 1},
+		Payload: []byte("v=0\r\no=- 1234 1 IN IP4 192.0.2.1\r\n"),
+	})
+	v2 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 2},
+		Payload: []byte("v=0\r\no=- 1234 2 IN IP4 192.0.2.1\r\n"),
+	})
+	other := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 3},
+		Payload: []byte("v=0\r\no=- 5678 1 IN IP4 192.0.2.1\r\n"),
+	})
+	deletion := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 2, MessageType: Deletion},
+		Payload: []byte("v=0\r\no=- 1234 2 IN IP4 192.0.2.1\r\n"),
+	})
+
+	d.Handle(v1, now)
+	d.Handle(v1, now.Add(time.Minute))
+	d.Handle(v2, now.Add(2*time.Minute))
+	d.Handle(other, now.Add(2*time.Minute))
+	d.Handle(deletion, now.Add(3*time.Minute))
+	d.Expire(now.Add(3 * time.Hour))
+
+	want := []Event{
+		{Type: SessionAdded, Packet: v1},
+		{Type: SessionModified, Packet: v2, Previous: v1},
+		{Type: SessionAdded, Packet: other},
+		{Type: SessionDeleted, Packet: deletion, Previous: v2},
+		{Type: SessionExpired, Packet: other},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d: %v", len(want), len(events), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: expected %s, got %s", i, want[i].Type, events[i].Type)
+		}
+	}
+
+	unsubscribe()
+	d.Handle(v1, now.Add(4*time.Hour))
+
+	if len(events) != len(want) {
+		t.Errorf("expected no events after unsubscribing, got %d", len(events)-len(want))
+	}
+}
+
+func TestDirectoryLateDeletion(t *testing.T) {
+	now := time.Now()
+	d := NewDirectory()
+
+	var events []EventType
+	d.Subscribe(func(e Event) {
+		events = append(events, e.Type)
+	})
+
+	v1 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 1},
+		Payload: []byte("v=0\r\no=- 1234 1 IN IP4 192.0.2.1\r\n"),
+	})
+	v2 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 2},
+		Payload: []byte("v=0\r\no=- 1234 2 IN IP4 192.0.2.1\r\n"),
+	})
+	deletion := v1.Clone()
+	deletion.MessageType = Deletion
+
+	// The deletion of the previous version arrives after the new version
+	d.Handle(v1, now)
+	d.Handle(v2, now)
+	d.Handle(deletion, now)
+
+	want := []EventType{SessionAdded, SessionModified}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Errorf("expected events %v, got %v", want, events)
+	}
+
+	if _, ok := d.Lookup(v2.AnnouncementKey()); !ok || len(d.Sessions()) != 1 {
+		t.Errorf("expected the current version to be kept, got %d sessions", len(d.Sessions()))
+	}
+}
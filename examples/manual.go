@@ -7,8 +7,8 @@ import (
 )
 
 func main() {
-	// Resolve the address
-	raddr, err := net.ResolveUDPAddr("udp", "224.0.0.255:9875")
+	// Find the SAP group of the session
+	raddr, err := sap.GroupFor(net.ParseIP("239.65.45.154"))
 	if err != nil {
 		panic(err)
 	}
@@ -46,7 +46,7 @@ a=mediaclk:direct=142410716`
 			AuthenticationLength: 0,
 			AuthenticationData:   []uint32{},
 			MessageIDHash:        sap.ComputeMsgIdHash(payload),
-			OriginatingSource:    raddr.IP,
+			OriginatingSource:    net.ParseIP("169.254.98.63"),
 			PayloadType:          "",
 		},
 		Payload: payload,
@@ -4,12 +4,12 @@ import (
 	"net"
 
 	"github.com/openaudiocollective/sap"
-	"github.com/pion/sdp"
+	"github.com/pion/sdp/v3"
 )
 
 func main() {
-	// Resolve the address
-	raddr, err := net.ResolveUDPAddr("udp", "224.0.0.255:9875")
+	// Find the SAP group of the session
+	raddr, err := sap.GroupFor(net.ParseIP("239.65.45.154"))
 	if err != nil {
 		panic(err)
 	}
@@ -37,7 +37,7 @@ func main() {
 	*/
 
 	inf := sdp.Information("2 channels: TxChan 0, TxChan 1")
-	rng := 32
+	ttl := 32
 	sess := sdp.SessionDescription{
 		Version: 0,
 		Origin: sdp.Origin{
@@ -54,8 +54,8 @@ func main() {
 			NetworkType: "IN",
 			AddressType: "IP4",
 			Address: &sdp.Address{
-				IP:    net.ParseIP("239.65.45.154"),
-				Range: &rng,
+				Address: "239.65.45.154",
+				TTL:     &ttl,
 			},
 		},
 		TimeDescriptions: []sdp.TimeDescription{
@@ -97,9 +97,7 @@ func main() {
 		},
 	}
 
-	payload := []byte(sess.Marshal())
-
-	pckt, err := sap.NewPacket(payload, *raddr)
+	pckt, err := sap.NewSessionAnnouncement(&sess, net.UDPAddr{IP: net.ParseIP("169.254.98.63")})
 	if err != nil {
 		panic(err)
 	}
@@ -7,8 +7,8 @@ import (
 )
 
 func main() {
-	// Resolve the address
-	raddr, err := net.ResolveUDPAddr("udp", "224.0.0.255:9875")
+	// Find the SAP group of the session
+	raddr, err := sap.GroupFor(net.ParseIP("239.65.45.154"))
 	if err != nil {
 		panic(err)
 	}
@@ -35,7 +35,7 @@ a=mediaclk:direct=142410716`
 
 	payload := []byte(s)
 
-	pckt, err := sap.NewPacket(payload, *raddr)
+	pckt, err := sap.NewPacket(payload, net.UDPAddr{IP: net.ParseIP("169.254.98.63")})
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,121 @@
+package sap
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Export writes the SDP description of every application/sdp session of the directory to a file of dir,
+// named after its originating source and message id hash, such as 192.0.2.1-12345.sdp.
+// The colons of IPv6 sources are replaced by underscores. A file is replaced by that of the new version
+// when the session is modified, and removed when the session is deleted or expires.
+//
+// Files are written to a temporary file first and renamed, so readers never see a partial description.
+// The sessions already in the directory are written right away. Errors writing or removing files
+// are passed to errorHandler if it is not nil. The returned function stops the export, leaving the files in place.
+func (d *Directory) Export(dir string, errorHandler func(error)) (stop func()) {
+	e := &exporter{dir: dir, errorHandler: errorHandler}
+
+	// Hold the exporter until the current sessions are written, so the events that follow apply after them
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	packets, stop := d.subscribe(e.handle, true)
+	for _, p := range packets {
+		e.write(p)
+	}
+
+	return stop
+}
+
+type exporter struct {
+	mu           sync.Mutex
+	dir          string
+	errorHandler func(error)
+}
+
+func (e *exporter) handle(event Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch event.Type {
+	case SessionAdded:
+		e.write(event.Packet)
+	case SessionModified:
+		e.write(event.Packet)
+		e.remove(event.Previous)
+	case SessionDeleted:
+		e.remove(event.Previous)
+	case SessionExpired:
+		e.remove(event.Packet)
+	}
+}
+
+// write atomically writes the SDP description of p to its file,
+// without the byte order mark or whitespace some announcers put in front of it.
+func (e *exporter) write(p *Packet) {
+	if !p.carriesSDP() {
+		return
+	}
+
+	e.report(writeFileAtomic(filepath.Join(e.dir, exportName(p)), trimSDPQuirks(p.Payload)))
+}
+
+// remove removes the file of p, if there is one.
+func (e *exporter) remove(p *Packet) {
+	if p == nil || !p.carriesSDP() {
+		return
+	}
+
+	if err := os.Remove(filepath.Join(e.dir, exportName(p))); !errors.Is(err, fs.ErrNotExist) {
+		e.report(err)
+	}
+}
+
+func (e *exporter) report(err error) {
+	if err != nil && e.errorHandler != nil {
+		e.errorHandler(err)
+	}
+}
+
+// exportName returns the name of the file the session announced by p is exported to.
+func exportName(p *Packet) string {
+	key := p.AnnouncementKey()
+
+	return fmt.Sprintf("%s-%d.sdp", strings.ReplaceAll(key.Source.String(), ":", "_"), key.MessageIDHash)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it to path.
+// The temporary file starts with a dot and does not end in .sdp, so it is skipped by a FolderWatcher.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// CreateTemp creates files readable by the owner only
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
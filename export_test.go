@@ -0,0 +1,121 @@
+package sap
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDirectoryExport(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	d := NewDirectory()
+
+	v1 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 1},
+		Payload: []byte("v=0\r\no=- 1234 1 IN IP4 192.0.2.1\r\n"),
+	})
+	v2 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 2},
+		Payload: []byte("v=0\r\no=- 1234 2 IN IP4 192.0.2.1\r\n"),
+	})
+	ipv6 := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 3, AddressType: IPv6, OriginatingSource: net.ParseIP("2001:db8::68")},
+		Payload: []byte("v=0\r\no=- 5678 1 IN IP6 2001:db8::68\r\n"),
+	})
+	other := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 4, PayloadType: "text/plain"},
+		Payload: []byte("hello"),
+	})
+
+	// Sessions already known are exported right away
+	d.Handle(v1, now)
+
+	var errs []error
+	stop := d.Export(dir, func(err error) {
+		errs = append(errs, err)
+	})
+
+	expectFiles := func(want ...string) {
+		t.Helper()
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir failed with error: %v", err)
+		}
+
+		got := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			got = append(got, entry.Name())
+		}
+		sort.Strings(got)
+
+		if len(got) != len(want) {
+			t.Fatalf("expected files %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected files %v, got %v", want, got)
+			}
+		}
+	}
+
+	expectFiles("192.0.2.1-1.sdp")
+
+	d.Handle(ipv6, now)
+	d.Handle(other, now)
+	expectFiles("192.0.2.1-1.sdp", "2001_db8__68-3.sdp")
+
+	// A new version replaces the file of the previous one
+	d.Handle(v2, now)
+	expectFiles("192.0.2.1-2.sdp", "2001_db8__68-3.sdp")
+
+	data, err := os.ReadFile(filepath.Join(dir, "192.0.2.1-2.sdp"))
+	if err != nil {
+		t.Fatalf("ReadFile failed with error: %v", err)
+	}
+	if string(data) != string(v2.Payload) {
+		t.Errorf("expected %q, got %q", v2.Payload, data)
+	}
+
+	deletion := v2.Clone()
+	deletion.MessageType = Deletion
+	d.Handle(deletion, now)
+	expectFiles("2001_db8__68-3.sdp")
+
+	d.Expire(now.Add(2 * MinSessionTimeout))
+	expectFiles()
+
+	stop()
+	d.Handle(v1, now)
+	expectFiles()
+
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestDirectoryExportByteOrderMark(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDirectory()
+
+	stop := d.Export(dir, nil)
+	defer stop()
+
+	p := CreateMockPacket(Packet{
+		Header:  Header{MessageIDHash: 1, PayloadTypeSource: PayloadTypeQuirk},
+		Payload: []byte("\xEF\xBB\xBFv=0\r\no=- 1234 1 IN IP4 192.0.2.1\r\n"),
+	})
+	d.Handle(p, time.Now())
+
+	data, err := os.ReadFile(filepath.Join(dir, "192.0.2.1-1.sdp"))
+	if err != nil {
+		t.Fatalf("ReadFile failed with error: %v", err)
+	}
+	if want := "v=0\r\no=- 1234 1 IN IP4 192.0.2.1\r\n"; string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+}
@@ -1,9 +1,31 @@
 package sap
 
-import "net"
+import (
+	"net"
+	"net/netip"
+	"time"
+)
 
+// Option sets a field of the Header of a Packet created by NewPacket
 type Option func(*Header)
 
+// PacketOption configures a Packet created by NewPacket.
+// Every Option is a PacketOption, options such as WithEncryption or WithSigner need the whole Packet.
+type PacketOption interface {
+	applyToPacket(*Packet)
+}
+
+func (o Option) applyToPacket(p *Packet) {
+	o(&p.Header)
+}
+
+// packetOption is a PacketOption setting more than the Header
+type packetOption func(*Packet)
+
+func (o packetOption) applyToPacket(p *Packet) {
+	o(p)
+}
+
 // Set the MessageType of the Header struct
 func WithMessageType(msgType MessageType) Option {
 	return func(h *Header) {
@@ -18,9 +40,36 @@ func WithPayloadType(payloadType string) Option {
 	}
 }
 
+// Compress the payload type and payload using zlib
+func WithCompression() Option {
+	return func(h *Header) {
+		h.Compressed = 1
+	}
+}
+
+// Encrypt the payload type and payload with the Cipher, identified by keyID.
+// The timeout tells listeners without the key when the session ends, it can be left zero.
+func WithEncryption(keyID uint32, timeout time.Time, c Cipher) PacketOption {
+	return packetOption(func(p *Packet) {
+		p.Encrypted = 1
+		p.Encryption = &Encryption{
+			KeyID:   keyID,
+			Timeout: timeout,
+			Cipher:  c,
+		}
+	})
+}
+
+// Sign the packet with the Signer when it is marshaled
+func WithSigner(s Signer) PacketOption {
+	return packetOption(func(p *Packet) {
+		p.Signer = s
+	})
+}
+
 // Factory function for creating a new SAP packet
 // Creates an IPv4, unencrypted, uncompressed, unauthenticated, SAP/SDP announcement packet by default
-func NewPacket(payload []byte, originatingSource net.UDPAddr, opts ...Option) (Packet, error) {
+func NewPacket(payload []byte, originatingSource net.UDPAddr, opts ...PacketOption) (Packet, error) {
 	header := Header{
 		Version:              1,
 		AddressType:          0,
@@ -30,7 +79,7 @@ func NewPacket(payload []byte, originatingSource net.UDPAddr, opts ...Option) (P
 		Compressed:           0,
 		AuthenticationLength: 0,
 		AuthenticationData:   []uint32{},
-		MessageIDHash:        ComputeMsgIdHash(payload),
+		MessageIDHash:        CRCHash{}.MessageIDHash(payload),
 		OriginatingSource:    originatingSource.IP,
 		PayloadType:          "",
 	}
@@ -42,13 +91,28 @@ func NewPacket(payload []byte, originatingSource net.UDPAddr, opts ...Option) (P
 		header.AddressType = IPv6
 	}
 
-	// Apply the options to the header
+	packet := Packet{
+		Header:  header,
+		Payload: payload,
+	}
+
+	// Apply the options to the packet
 	for _, opt := range opts {
-		opt(&header)
+		opt.applyToPacket(&packet)
 	}
 
-	return Packet{
-		Header:  header,
-		Payload: payload,
-	}, nil
+	return packet, nil
+}
+
+// NewPacketFromAddr is NewPacket with the originating source given as a netip.Addr.
+// The address type is IPv4 if originatingSource is an IPv4 or IPv4-mapped IPv6 address, IPv6 otherwise.
+func NewPacketFromAddr(payload []byte, originatingSource netip.Addr, opts ...PacketOption) (Packet, error) {
+	packet, err := NewPacket(payload, net.UDPAddr{}, opts...)
+	if err != nil {
+		return Packet{}, err
+	}
+
+	packet.SetSource(originatingSource)
+
+	return packet, nil
 }
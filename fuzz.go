@@ -3,33 +3,21 @@
 
 package sap
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 func Fuzz(data []byte) int {
-	predefined_errs := []error{
-		errBufTooSmallForFlags,
-		errBufTooSmallForAuthLength,
-		errBufTooSmallForAuthData,
-		errBufTooSmallForMsgIdHash,
-		errBufTooSmallForIPv4,
-		errBufTooSmallForIPv6,
-		errBufTooSmallForPayload,
-		errBufTooSmallForPayloadType,
-		errBufTooSmallForHeader,
-		errNoTrailingByteFound,
-		errInvalidIPOnHeader,
-	}
-
 	p := Packet{}
 	err := p.Unmarshal(data)
 
 	if err != nil {
 		// Now check if it's one of your defined errors
-		for _, predeferr := range predefined_errs {
-			if predeferr.Error() == err.Error() {
-				fmt.Printf("The error '%s' is one of the predefined errors\n", err)
-				return 1 // the input is invalid, no need to test it further
-			}
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			fmt.Printf("The error '%s' is one of the predefined errors\n", err)
+			return 1 // the input is invalid, no need to test it further
 		}
 		return 0 // the input is invalid, no need to test it further
 	}
@@ -37,11 +25,9 @@ func Fuzz(data []byte) int {
 	_, err = p.MarshalTo(data)
 	if err != nil {
 		// Now check if it's one of your defined errors
-		for _, predeferr := range predefined_errs {
-			if predeferr.Error() == err.Error() {
-				fmt.Printf("The error '%s' is one of the predefined errors\n", err)
-				return 1 // the input is invalid, no need to test it further
-			}
+		if errors.Is(err, errBufTooSmallForHeader) || errors.Is(err, errBufTooSmallForPayload) || errors.Is(err, errInvalidIPOnHeader) {
+			fmt.Printf("The error '%s' is one of the predefined errors\n", err)
+			return 1 // the input is invalid, no need to test it further
 		}
 		return 0 // the input is invalid, no need to test it further
 	}
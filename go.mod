@@ -1,3 +1,16 @@
 module github.com/openaudiocollective/sap
 
 go 1.20
+
+require (
+	github.com/ProtonMail/go-crypto v1.1.6
+	github.com/pion/sdp/v3 v3.0.10
+	golang.org/x/net v0.19.0
+)
+
+require (
+	github.com/cloudflare/circl v1.3.7 // indirect
+	github.com/pion/randutil v0.1.0 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+)
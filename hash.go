@@ -1,7 +1,11 @@
 package sap
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"hash/crc32"
+	"math/rand"
+	"sync/atomic"
 )
 
 func ComputeMsgIdHash(payload []byte) uint16 {
@@ -11,3 +15,77 @@ func ComputeMsgIdHash(payload []byte) uint16 {
 	// when you do a bitwise AND with 0xFFFF, it essentially keeps only the last 16 bits of the hash and discards the rest.
 	return uint16(hash & 0xFFFF)
 }
+
+// HashStrategy computes the message id hash of a packet.
+// RFC 2974 only requires the hash to be unique among the sessions of an announcer and to change
+// when a session is modified. The hash returned is never zero, as listeners may then ignore the packet.
+type HashStrategy interface {
+	MessageIDHash(payload []byte) uint16
+}
+
+// CRCHash is the default HashStrategy, a CRC32 of the payload truncated to 16 bits, see ComputeMsgIdHash.
+// Different payloads can have the same hash, the Announcer resolves these collisions.
+type CRCHash struct{}
+
+func (CRCHash) MessageIDHash(payload []byte) uint16 {
+	return nonZeroHash(ComputeMsgIdHash(payload))
+}
+
+// SHA256Hash takes the first 16 bits of the SHA-256 digest of the payload.
+type SHA256Hash struct{}
+
+func (SHA256Hash) MessageIDHash(payload []byte) uint16 {
+	sum := sha256.Sum256(payload)
+
+	return nonZeroHash(binary.BigEndian.Uint16(sum[:2]))
+}
+
+// CounterHash hands out increasing hashes, whatever the payload. Shared by the packets of an announcer,
+// every version of a session gets a hash greater than the previous one, until the counter wraps around.
+type CounterHash struct {
+	last atomic.Uint32
+}
+
+// NewCounterHash returns a CounterHash starting at a random value,
+// so that the hashes of a restarted announcer do not match those it sent before.
+func NewCounterHash() *CounterHash {
+	c := &CounterHash{}
+	c.last.Store(uint32(rand.Intn(1 << 16)))
+
+	return c
+}
+
+func (c *CounterHash) MessageIDHash([]byte) uint16 {
+	for {
+		if hash := uint16(c.last.Add(1)); hash != 0 {
+			return hash
+		}
+	}
+}
+
+// Compute the message id hash with the strategy, instead of CRCHash.
+// The strategy is also used if the packet is marshaled with a zero hash.
+func WithHashStrategy(s HashStrategy) PacketOption {
+	return packetOption(func(p *Packet) {
+		p.HashStrategy = s
+		p.MessageIDHash = s.MessageIDHash(p.Payload)
+	})
+}
+
+// messageIDHash computes the hash of the packet with its HashStrategy, CRCHash if it has none.
+func (p Packet) messageIDHash() uint16 {
+	if p.HashStrategy == nil {
+		return CRCHash{}.MessageIDHash(p.Payload)
+	}
+
+	return p.HashStrategy.MessageIDHash(p.Payload)
+}
+
+// nonZeroHash replaces a zero hash, which RFC 2974 reserves for announcers that do not compute one
+func nonZeroHash(hash uint16) uint16 {
+	if hash == 0 {
+		return 1
+	}
+
+	return hash
+}
@@ -41,3 +41,51 @@ func TestValidateMsgIdHash(t *testing.T) {
 		})
 	}
 }
+
+func TestHashStrategies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		strategy HashStrategy
+		payload  []byte
+		want     uint16
+	}{
+		{
+			name:     "CRCNeverZero",
+			strategy: CRCHash{},
+			payload:  []byte{},
+			want:     1,
+		},
+		{
+			name:     "CRC",
+			strategy: CRCHash{},
+			payload:  []byte("v=0\r\n"),
+			want:     ComputeMsgIdHash([]byte("v=0\r\n")),
+		},
+		{
+			name:     "SHA256",
+			strategy: SHA256Hash{},
+			payload:  []byte("v=0\r\n"),
+			want:     0x4cf5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.strategy.MessageIDHash(tc.payload)
+			if tc.want != got {
+				t.Errorf("expected %#x, got %#x", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCounterHash(t *testing.T) {
+	c := &CounterHash{}
+	c.last.Store(0xFFFE)
+
+	for _, want := range []uint16{0xFFFF, 1, 2} {
+		if got := c.MessageIDHash(nil); got != want {
+			t.Errorf("expected %#x, got %#x", want, got)
+		}
+	}
+}
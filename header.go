@@ -3,8 +3,8 @@ package sap
 import (
 	"encoding/binary"
 	"io"
-	"mime"
 	"net"
+	"net/netip"
 )
 
 // Header represents an SAP packet header
@@ -25,6 +25,7 @@ type Header struct {
 
 	// If the encryption bit is set to 1, the payload of the SAP packet is encrypted.
 	// If this bit is 0 the packet is not encrypted.
+	// The payload type is encrypted along with the payload, see Packet.Encryption.
 	Encrypted uint8
 
 	// If the compressed bit is set to 1, the payload is
@@ -44,6 +45,7 @@ type Header struct {
 	// It MUST be unique for each session announced by a particular SAP announcer
 	// and it MUST be changed if the session description is modified
 	// (and a session deletion message SHOULD be sent for the old version of the session).
+	// Announcer.Update and Session.Update take care of both.
 	//
 	// SAP listeners MAY silently discard messages if the message
 	// identifier hash is set to zero.
@@ -63,6 +65,10 @@ type Header struct {
 	//
 	// Technically, it is part of the Payload, but makes more sense to parse it with the rest of the header
 	PayloadType string
+
+	// How the payload type was found when the header was unmarshaled, see PayloadTypePolicy.
+	// It is not sent.
+	PayloadTypeSource PayloadTypeSource
 }
 
 // If this is an announcement or deletion packet
@@ -82,7 +88,7 @@ const (
 )
 
 const (
-	versionShift     = 5    // Number of bits to shift for the version bit
+	versionShift     = 5    // Number of bits to shift for the version bits
 	addressShift     = 4    // Number of bits to shift for the address type bit
 	reservedShift    = 3    // Number of bits to shift for the reserved bit
 	messageTypeShift = 2    // Number of bits to shift for the message type bit
@@ -114,8 +120,8 @@ func (h Header) MarshalSize() int {
 		size += 16
 	}
 
-	if h.PayloadType == "" {
-		// payloadType is omitted
+	if h.PayloadType == "" || !h.payloadTypeInHeader() {
+		// payloadType is omitted or travels inside the compressed or encrypted payload
 		return size
 	}
 
@@ -128,7 +134,21 @@ func (h Header) MarshalSize() int {
 }
 
 // Unmarshal parses the passed byte slice and stores the result in the Header.
-func (h *Header) Unmarshal(buf []byte) error {
+// The payload type is detected with DefaultPayloadTypePolicy unless WithPayloadTypePolicy is passed,
+// and the header is checked with the policy passed using WithDecodePolicy, Lenient by default.
+// The other options only apply to Packet.Unmarshal and are ignored.
+func (h *Header) Unmarshal(buf []byte, opts ...UnmarshalOption) error {
+	config := newUnmarshalConfig(opts)
+
+	_, err := h.unmarshal(buf, config.policy, config.payloadTypePolicy)
+
+	return err
+}
+
+// unmarshal parses the header at the start of buf, checking it with policy
+// and detecting the payload type with payloadTypePolicy.
+// It returns the size of the header, payload type included.
+func (h *Header) unmarshal(buf []byte, policy Policy, payloadTypePolicy PayloadTypePolicy) (int, error) {
 	/*
 	    0                   1                   2                   3
 	    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
@@ -151,12 +171,14 @@ func (h *Header) Unmarshal(buf []byte) error {
 	currentPosition := 0
 
 	if len(buf[currentPosition:]) < 1 {
-		return errBufTooSmallForFlags
+		return 0, truncated(ErrBufTooSmallForFlags, "Flags", buf, currentPosition, 1)
 	}
 
-	// The first two bits are always zero
-	// The third bit contains the Version Number
-	h.Version = (buf[currentPosition] >> versionShift) & oneBitMask
+	// The first three bits contain the Version Number, 1 or 0 for SAPv0
+	h.Version = buf[currentPosition] >> versionShift
+	if h.Version > 1 {
+		return 0, malformed(ErrInvalidVersion, "Flags", 0)
+	}
 
 	// The fourth bit is the address type bit
 	h.AddressType = AddressType(buf[currentPosition]>>addressShift) & oneBitMask
@@ -178,23 +200,28 @@ func (h *Header) Unmarshal(buf []byte) error {
 
 	// Authentication Length
 	if len(buf[currentPosition:]) < 1 {
-		return errBufTooSmallForAuthLength
+		return 0, truncated(ErrBufTooSmallForAuthLength, "Authentication Length", buf, currentPosition, 1)
 	}
 	h.AuthenticationLength = buf[currentPosition]
 	currentPosition++
 
 	// Message Id Hash
 	if len(buf[currentPosition:]) < 2 {
-		return errBufTooSmallForMsgIdHash
+		return 0, truncated(ErrBufTooSmallForMsgIdHash, "Message Identifier Hash", buf, currentPosition, 2)
 	}
 	h.MessageIDHash = binary.BigEndian.Uint16(buf[currentPosition : currentPosition+2])
 	currentPosition += 2
 
+	// SAPv0 only has IPv4 originating sources
+	if h.Version == 0 && h.AddressType != IPv4 {
+		return 0, malformed(ErrLegacyIPv6, "Flags", 0)
+	}
+
 	// Originating Source
 	switch h.AddressType {
 	case 0: // Expecting IPv4
 		if len(buf[currentPosition:]) < 4 {
-			return errBufTooSmallForIPv4
+			return 0, truncated(ErrBufTooSmallForIPv4, "Originating Source", buf, currentPosition, 4)
 		}
 
 		h.OriginatingSource = net.IPv4(buf[currentPosition], buf[currentPosition+1], buf[currentPosition+2], buf[currentPosition+3])
@@ -202,7 +229,7 @@ func (h *Header) Unmarshal(buf []byte) error {
 
 	case 1: // Expecting IPv6
 		if len(buf[currentPosition:]) < 16 {
-			return errBufTooSmallForIPv6
+			return 0, truncated(ErrBufTooSmallForIPv6, "Originating Source", buf, currentPosition, 16)
 		}
 
 		h.OriginatingSource = net.IP(buf[currentPosition : currentPosition+16])
@@ -212,7 +239,7 @@ func (h *Header) Unmarshal(buf []byte) error {
 	// Authentication Data
 	if h.AuthenticationLength != 0 {
 		if len(buf[currentPosition:]) < int(h.AuthenticationLength)*4 {
-			return errBufTooSmallForAuthData
+			return 0, truncated(ErrBufTooSmallForAuthData, "Authentication Data", buf, currentPosition, int(h.AuthenticationLength)*4)
 		}
 
 		h.AuthenticationData = make([]uint32, h.AuthenticationLength)
@@ -222,40 +249,43 @@ func (h *Header) Unmarshal(buf []byte) error {
 		}
 	}
 
-	if len(buf[currentPosition:]) < 3 || (len(buf[currentPosition:]) >= 3 && string(buf[currentPosition:currentPosition+3]) == "v=0") {
-		// whether there's is no payload or the payload type has been omitted
-		// and we are already in the payload
-		h.PayloadType = ""
-	} else {
-		// either there is a payload type in the header
-		// or the payload type is "application/sdp" (implicit because it's omitted)
-		// and the payload itself is not SDP (because it doesn't start with "v=0")
-
-		i := 0
-		for ; i < len(buf[currentPosition:]); i++ {
-			if buf[currentPosition+i] == 0 { // looking for the trailing zero byte
-				break
-			}
+	if policy == Strict {
+		if field, offset, err := h.validate(false); err != nil {
+			return 0, malformed(err, field, offset)
 		}
+	}
 
-		if i == len(buf[currentPosition:]) && buf[currentPosition+(i-1)] != 0 {
-			// we traversed the whole buffer but didnt find a trailing byte
-			return errNoTrailingByteFound
-		}
+	h.PayloadType = ""
+	h.PayloadTypeSource = PayloadTypeUnknown
 
-		mediaType, _, err := mime.ParseMediaType(string(buf[currentPosition : currentPosition+i])) // doesn't include the trailing zero
-		if err != nil {
-			// the string until the trailing zero is not a valid mime media type
-			// this indicates the payload type has been omitted (thus being application/sdp) and we are already in the payload
-			// since we already checked and the start of the payload is not "v=0", the payload is not of type "application/sdp"
-			return err
-		}
+	if h.Version == 0 {
+		// SAPv0 packets have no payload type, the payload is always an SDP description
+		h.PayloadTypeSource = PayloadTypeImplicit
+		return currentPosition, nil
+	}
 
-		// Payload Type
-		h.PayloadType = mediaType
+	if !h.payloadTypeInHeader() {
+		// The payload type is part of the compressed or encrypted payload
+		// and is recovered by Packet.Unmarshal once decrypted and inflated
+		return currentPosition, nil
 	}
 
-	return nil
+	payloadType, n, source, err := payloadTypePolicy.detect(buf[currentPosition:])
+	if err != nil {
+		return 0, malformed(err, "Payload Type", currentPosition)
+	}
+
+	// Payload Type
+	h.PayloadType = payloadType
+	h.PayloadTypeSource = source
+
+	return currentPosition + n, nil
+}
+
+// payloadTypeInHeader reports whether the payload type is sent in the clear right after the header.
+// Otherwise it is compressed and/or encrypted together with the payload, or not sent at all by SAPv0.
+func (h Header) payloadTypeInHeader() bool {
+	return h.Version != 0 && h.Compressed == 0 && h.Encrypted == 0
 }
 
 // Marshal serializes the header into bytes.
@@ -271,6 +301,8 @@ func (h Header) Marshal() (buf []byte, err error) {
 
 // MarshalTo serializes the header and writes to the buffer.
 // It returns the number of bytes read n and any error.
+// The header is written as is, except for the authentication length which is that of the authentication data.
+// Validate checks it first, as Packet.Marshal does with WithEncodePolicy(Strict).
 func (h Header) MarshalTo(buf []byte) (n int, err error) {
 	/*
 	    0                   1                   2                   3
@@ -296,33 +328,38 @@ func (h Header) MarshalTo(buf []byte) (n int, err error) {
 		return 0, io.ErrShortBuffer
 	}
 
+	// The authentication length is always that of the authentication data written
+	if len(h.AuthenticationData) > 255 {
+		return 0, errAuthDataTooLarge
+	}
+
 	// This is the number of bytes marshalled
 	currentPosition := 0
 
 	// The first two bits are always zero
 	// The third bit contains the Version Number
-	buf[currentPosition] = (h.Version << versionShift)
+	buf[currentPosition] = (h.Version & oneBitMask) << versionShift
 
 	// The fourth bit is the address type bit
-	buf[currentPosition] |= byte((h.AddressType << addressShift))
+	buf[currentPosition] |= byte((h.AddressType & oneBitMask) << addressShift)
 
 	// The fifth bit is the reserved bit
-	buf[currentPosition] |= (h.Reserved << reservedShift)
+	buf[currentPosition] |= (h.Reserved & oneBitMask) << reservedShift
 
 	// The sixth bit is the message type bit
-	buf[currentPosition] |= byte((h.MessageType << messageTypeShift))
+	buf[currentPosition] |= byte((h.MessageType & oneBitMask) << messageTypeShift)
 
 	// The seventh bit is the encrypted bit
-	buf[currentPosition] |= (h.Encrypted << encryptedShift)
+	buf[currentPosition] |= (h.Encrypted & oneBitMask) << encryptedShift
 
 	// The last bit is the compressed bit
-	buf[currentPosition] |= (h.Compressed << compressedShift)
+	buf[currentPosition] |= (h.Compressed & oneBitMask) << compressedShift
 
 	// First byte
 	currentPosition++
 
 	// Authentication Length
-	buf[currentPosition] = h.AuthenticationLength
+	buf[currentPosition] = byte(len(h.AuthenticationData))
 	currentPosition++
 
 	// Message Id Hash
@@ -330,6 +367,10 @@ func (h Header) MarshalTo(buf []byte) (n int, err error) {
 	currentPosition += 2
 
 	// Originating Source
+	if h.Version == 0 && h.OriginatingSource.To4() == nil {
+		return 0, ErrLegacyIPv6
+	}
+
 	if h.OriginatingSource.To4() != nil {
 		copy(buf[currentPosition:], h.OriginatingSource.To4())
 		currentPosition += 4
@@ -347,7 +388,8 @@ func (h Header) MarshalTo(buf []byte) (n int, err error) {
 	}
 
 	// Payload Type
-	if h.PayloadType != "" {
+	// When the payload is compressed or encrypted the payload type is processed along with it by Packet
+	if h.PayloadType != "" && h.payloadTypeInHeader() {
 		payloadTypeBytes := []byte(h.PayloadType)
 		for i := range payloadTypeBytes {
 			buf[currentPosition+i] = payloadTypeBytes[i]
@@ -385,3 +427,32 @@ func (h Header) Clone() Header {
 
 	return clone
 }
+
+// Source returns the originating source as a netip.Addr.
+// IPv4 sources are returned as IPv4 addresses, not IPv4-mapped IPv6 addresses.
+// It returns the zero Addr if the originating source is not a valid IP address.
+func (h Header) Source() netip.Addr {
+	addr, ok := netip.AddrFromSlice(h.OriginatingSource)
+	if !ok {
+		return netip.Addr{}
+	}
+
+	if h.AddressType == IPv4 {
+		return addr.Unmap()
+	}
+
+	return addr
+}
+
+// SetSource sets the originating source, and the address type from the kind of address.
+// IPv4-mapped IPv6 addresses are sent as IPv4 addresses.
+func (h *Header) SetSource(addr netip.Addr) {
+	addr = addr.Unmap()
+
+	h.OriginatingSource = net.IP(addr.AsSlice())
+	if addr.Is4() {
+		h.AddressType = IPv4
+	} else {
+		h.AddressType = IPv6
+	}
+}
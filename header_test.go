@@ -1,7 +1,9 @@
 package sap
 
 import (
+	"errors"
 	"net"
+	"net/netip"
 	"reflect"
 	"testing"
 )
@@ -75,45 +77,50 @@ func TestHeaderUnmarshalErrors(t *testing.T) {
 		{
 			name:          "BufTooSmallForFlags",
 			input:         make([]byte, 0),
-			expectedError: errBufTooSmallForFlags,
+			expectedError: ErrBufTooSmallForFlags,
 		},
 		{
 			name:          "BufTooSmallForAuthLength",
 			input:         make([]byte, 1),
-			expectedError: errBufTooSmallForAuthLength,
+			expectedError: ErrBufTooSmallForAuthLength,
 		},
 		{
 			name:          "BufTooSmallForMsgIdHash",
 			input:         make([]byte, 2),
-			expectedError: errBufTooSmallForMsgIdHash,
+			expectedError: ErrBufTooSmallForMsgIdHash,
 		},
 		{
 			name:          "BufTooSmallForForIPv4",
 			input:         make([]byte, 4),
-			expectedError: errBufTooSmallForIPv4,
+			expectedError: ErrBufTooSmallForIPv4,
 		},
 		{
 			name: "BufTooSmallForForIPv6",
 			// The address type bit is set to 1 and this byte slice has 16 bytes which is not enough for IPv6 address
 			input:         []byte{0x30, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
-			expectedError: errBufTooSmallForIPv6,
+			expectedError: ErrBufTooSmallForIPv6,
 		},
 		{
 			name:          "BufTooSmallForAuthDataWithIPv4",
 			input:         []byte{0x20, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
-			expectedError: errBufTooSmallForAuthData,
+			expectedError: ErrBufTooSmallForAuthData,
 		},
 		{
 			name: "BufTooSmallForAuthDataWithIPv6",
 			// The address type bit is set to 1 and this byte slice has 20 bytes which is enough for IPv6 address but not to AuthData
 			input:         []byte{0x30, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
-			expectedError: errBufTooSmallForAuthData,
+			expectedError: ErrBufTooSmallForAuthData,
+		},
+		{
+			name:          "InvalidVersion",
+			input:         []byte{0x40, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01, 'v', '=', '0'},
+			expectedError: ErrInvalidVersion,
 		},
 		{
 			name: "NoTrailingByteFound",
 			// There's more than one byte after the AuthenticationData and but none is 0 (the trailing byte)
 			input:         []byte{0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0x01},
-			expectedError: errNoTrailingByteFound,
+			expectedError: ErrNoTrailingByteFound,
 		},
 	}
 
@@ -121,13 +128,29 @@ func TestHeaderUnmarshalErrors(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockHeader := CreateMockHeader(Header{})
 			err := mockHeader.Unmarshal(tc.input)
-			if err != tc.expectedError {
+			if !errors.Is(err, tc.expectedError) {
 				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
 			}
 		})
 	}
 }
 
+// TestHeaderUnmarshalParseError checks that the failing field and its position are reported.
+func TestHeaderUnmarshalParseError(t *testing.T) {
+	mockHeader := CreateMockHeader(Header{})
+	err := mockHeader.Unmarshal([]byte{0x30, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, but got %v", err)
+	}
+
+	want := ParseError{Field: "Originating Source", Offset: 4, Expected: 16, Available: 8, Err: ErrBufTooSmallForIPv6}
+	if *parseErr != want {
+		t.Errorf("Expected %+v, but got %+v", want, *parseErr)
+	}
+}
+
 // TestHeaderMarshalAndUnmarshal checks that a header can be marshaled and then unmarshaled to its original state.
 func TestHeaderMarshalAndUnmarshal(t *testing.T) {
 	testCases := []struct {
@@ -166,13 +189,15 @@ func TestHeaderMarshalAndUnmarshal(t *testing.T) {
 		{
 			name: "Test 6: Payload Type application/sdp",
 			mockHeader: CreateMockHeader(Header{
-				PayloadType: "application/sdp",
+				PayloadType:       "application/sdp",
+				PayloadTypeSource: PayloadTypeExplicit,
 			}),
 		},
 		{
 			name: "Test 7: Payload Type application/json",
 			mockHeader: CreateMockHeader(Header{
-				PayloadType: "application/json",
+				PayloadType:       "application/json",
+				PayloadTypeSource: PayloadTypeExplicit,
 			}),
 		},
 	}
@@ -282,6 +307,65 @@ func TestHeaderClone(t *testing.T) {
 	}
 }
 
+// TestHeaderSource checks the conversions between the originating source and netip.Addr.
+func TestHeaderSource(t *testing.T) {
+	testCases := []struct {
+		name        string
+		addr        netip.Addr
+		addressType AddressType
+		ip          net.IP
+	}{
+		{
+			name:        "IPv4",
+			addr:        netip.MustParseAddr("192.0.2.1"),
+			addressType: IPv4,
+			ip:          net.ParseIP("192.0.2.1"),
+		},
+		{
+			name:        "IPv4MappedIPv6",
+			addr:        netip.MustParseAddr("::ffff:192.0.2.1"),
+			addressType: IPv4,
+			ip:          net.ParseIP("192.0.2.1"),
+		},
+		{
+			name:        "IPv6",
+			addr:        netip.MustParseAddr("2001:db8::68"),
+			addressType: IPv6,
+			ip:          net.ParseIP("2001:db8::68"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := CreateMockHeader(Header{})
+			h.SetSource(tc.addr)
+
+			if h.AddressType != tc.addressType {
+				t.Errorf("expected address type %v, got %v", tc.addressType, h.AddressType)
+			}
+			if !h.OriginatingSource.Equal(tc.ip) {
+				t.Errorf("expected originating source %v, got %v", tc.ip, h.OriginatingSource)
+			}
+			if got := h.Source(); got != tc.addr.Unmap() {
+				t.Errorf("expected source %v, got %v", tc.addr.Unmap(), got)
+			}
+
+			data, err := h.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			h2 := Header{}
+			if err := h2.Unmarshal(data); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+			if got := h2.Source(); got != tc.addr.Unmap() {
+				t.Errorf("expected unmarshaled source %v, got %v", tc.addr.Unmap(), got)
+			}
+		})
+	}
+}
+
 func CreateMockHeader(h Header) Header {
 	// Any fields with their zero values (0 for integers, nil for slices, and so on) will be filled in with default values.
 	h.Version = 1
@@ -0,0 +1,49 @@
+package sap
+
+// LegacyPolicy sets what a Listener does with SAPv0 packets, sent by sdr-era tools and older devices.
+// They have no payload type and only IPv4 originating sources, but are otherwise laid out as SAPv1 packets.
+type LegacyPolicy uint8
+
+const (
+	// Keep SAPv0 packets as they are, with a version of 0
+	LegacyAccept LegacyPolicy = iota
+
+	// Drop SAPv0 packets, reporting ErrLegacyVersion to the packet error handler
+	LegacyReject
+
+	// Turn SAPv0 packets into SAPv1 packets with an implicit application/sdp payload type
+	LegacyUpgrade
+)
+
+// Marshal the packet as a SAPv0 packet, for listeners that do not understand SAPv1.
+// The payload type is not sent and the originating source must be an IPv4 address.
+func WithLegacyVersion() Option {
+	return func(h *Header) {
+		h.Version = 0
+		h.PayloadType = ""
+	}
+}
+
+// Handle SAPv0 packets according to policy, LegacyAccept by default
+func WithLegacyPolicy(policy LegacyPolicy) ListenerOption {
+	return func(l *Listener) {
+		l.legacyPolicy = policy
+	}
+}
+
+// applyLegacyPolicy applies the policy to an unmarshaled packet.
+// It returns ErrLegacyVersion if the packet is rejected.
+func applyLegacyPolicy(p *Packet, policy LegacyPolicy) error {
+	if p.Version != 0 {
+		return nil
+	}
+
+	switch policy {
+	case LegacyReject:
+		return ErrLegacyVersion
+	case LegacyUpgrade:
+		p.Version = 1
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestLegacyMarshalAndUnmarshal(t *testing.T) {
+	p, err := NewPacket([]byte("o=- 1 1 IN IP4 192.0.2.1\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")},
+		WithPayloadType("application/sdp"), WithLegacyVersion())
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	if data[0]>>versionShift != 0 || len(data) != 8+len(p.Payload) {
+		t.Fatalf("expected a SAPv0 packet without payload type, got % x", data)
+	}
+
+	p2 := Packet{}
+	if err := p2.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	if p2.Version != 0 || p2.PayloadTypeSource != PayloadTypeImplicit || string(p2.Payload) != string(p.Payload) {
+		t.Errorf("unexpected version %d, payload type source %v and payload %q", p2.Version, p2.PayloadTypeSource, p2.Payload)
+	}
+
+	if err := p2.Unmarshal(data, WithDecodePolicy(Strict)); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("Expected error %v, but got %v", ErrInvalidVersion, err)
+	}
+}
+
+func TestLegacyIPv6(t *testing.T) {
+	p, err := NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("2001:db8::68")}, WithLegacyVersion())
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	if _, err := p.Marshal(); !errors.Is(err, ErrLegacyIPv6) {
+		t.Errorf("Expected error %v, but got %v", ErrLegacyIPv6, err)
+	}
+
+	h := Header{}
+	input := []byte{0x10, 0x00, 0x12, 0x34, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	if err := h.Unmarshal(input); !errors.Is(err, ErrLegacyIPv6) {
+		t.Errorf("Expected error %v, but got %v", ErrLegacyIPv6, err)
+	}
+}
+
+func TestApplyLegacyPolicy(t *testing.T) {
+	testCases := []struct {
+		name            string
+		version         uint8
+		policy          LegacyPolicy
+		expectedVersion uint8
+		expectedError   error
+	}{
+		{
+			name:            "Accept",
+			version:         0,
+			policy:          LegacyAccept,
+			expectedVersion: 0,
+		},
+		{
+			name:          "Reject",
+			version:       0,
+			policy:        LegacyReject,
+			expectedError: ErrLegacyVersion,
+		},
+		{
+			name:            "Upgrade",
+			version:         0,
+			policy:          LegacyUpgrade,
+			expectedVersion: 1,
+		},
+		{
+			name:            "RejectIgnoresVersion1",
+			version:         1,
+			policy:          LegacyReject,
+			expectedVersion: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := CreateMockPacket(Packet{})
+			p.Version = tc.version
+
+			err := applyLegacyPolicy(p, tc.policy)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+
+			if err == nil && p.Version != tc.expectedVersion {
+				t.Errorf("expected version %d, got %d", tc.expectedVersion, p.Version)
+			}
+		})
+	}
+}
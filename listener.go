@@ -0,0 +1,156 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// expiryCheckInterval is how often the Listener looks for timed out sessions
+const expiryCheckInterval = 10 * time.Second
+
+// ListenerOption configures a Listener created by NewListener
+type ListenerOption func(*Listener)
+
+// Decode received packets with the given options, for example WithKeyring or WithVerifiers
+func WithUnmarshalOptions(opts ...UnmarshalOption) ListenerOption {
+	return func(l *Listener) {
+		l.unmarshalOpts = append(l.unmarshalOpts, opts...)
+	}
+}
+
+// Call handler with the error of every packet that could not be decoded or was rejected, along with the address it came from
+func WithPacketErrorHandler(handler func(addr net.Addr, err error)) ListenerOption {
+	return func(l *Listener) {
+		l.errorHandler = handler
+	}
+}
+
+// Use directory to keep track of the sessions instead of a new one
+func WithDirectory(directory *Directory) ListenerOption {
+	return func(l *Listener) {
+		l.directory = directory
+	}
+}
+
+// Listener receives SAP packets from one or more sockets, typically joined
+// to the SAP groups of different scopes, and keeps a Directory of the announced sessions.
+type Listener struct {
+	conns         []net.PacketConn
+	directory     *Directory
+	unmarshalOpts []UnmarshalOption
+	errorHandler  func(addr net.Addr, err error)
+	legacyPolicy  LegacyPolicy
+}
+
+// NewListener returns a Listener reading from conns.
+func NewListener(conns []net.PacketConn, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		conns: conns,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.directory == nil {
+		l.directory = NewDirectory()
+	}
+
+	return l
+}
+
+// Directory returns the directory of the sessions heard by the listener.
+func (l *Listener) Directory() *Directory {
+	return l.directory
+}
+
+// Run reads packets from the sockets and expires sessions until ctx is done or a socket fails.
+// The sockets are not closed when it returns, and have no read deadline.
+func (l *Listener) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(l.conns))
+
+	for _, conn := range l.conns {
+		wg.Add(1)
+		go func(conn net.PacketConn) {
+			defer wg.Done()
+			if err := l.read(ctx, conn); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(conn)
+	}
+
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case now := <-ticker.C:
+			l.directory.Expire(now)
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	// Unblock the pending reads, then clear the deadline so the sockets can still be used
+	for _, conn := range l.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	wg.Wait()
+
+	for _, conn := range l.conns {
+		_ = conn.SetReadDeadline(time.Time{})
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+// read reads packets from conn until ctx is done or the socket fails.
+func (l *Listener) read(ctx context.Context, conn net.PacketConn) error {
+	buf := make([]byte, 65536)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return err
+		}
+
+		// The packet keeps referencing the data it was unmarshaled from
+		data := make([]byte, n)
+		copy(data, buf[:n])
+
+		p := &Packet{}
+		err = p.Unmarshal(data, l.unmarshalOpts...)
+		if err == nil {
+			err = applyLegacyPolicy(p, l.legacyPolicy)
+		}
+		if err != nil {
+			if l.errorHandler != nil {
+				l.errorHandler(addr, err)
+			}
+			continue
+		}
+
+		l.directory.Handle(p, time.Now())
+	}
+}
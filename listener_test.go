@@ -0,0 +1,92 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer conn.Close()
+
+	sender, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer sender.Close()
+
+	errs := make(chan error, 1)
+	l := NewListener([]net.PacketConn{conn}, WithPacketErrorHandler(func(addr net.Addr, err error) {
+		errs <- err
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+
+	p, err := NewPacket([]byte("v=0\r\ns=Session\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	if _, err := sender.WriteTo(data, conn.LocalAddr()); err != nil {
+		t.Fatalf("WriteTo failed with error: %v", err)
+	}
+
+	// An invalid packet is reported to the error handler
+	if _, err := sender.WriteTo([]byte{0x20}, conn.LocalAddr()); err != nil {
+		t.Fatalf("WriteTo failed with error: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrBufTooSmallForAuthLength) {
+			t.Errorf("Expected error %v, but got %v", ErrBufTooSmallForAuthLength, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the invalid packet to be reported")
+	}
+
+	// Packets on a socket are handled in order, so the announcement is in the directory by now
+	entry, ok := l.Directory().Lookup(p.AnnouncementKey())
+	if !ok {
+		t.Fatal("expected the session to be in the directory")
+	}
+	if string(entry.Packet.Payload) != "v=0\r\ns=Session\r\n" {
+		t.Errorf("unexpected payload %q", entry.Packet.Payload)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected error %v, but got %v", context.Canceled, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	// The socket can still be read from once Run has returned
+	if _, err := sender.WriteTo(data, conn.LocalAddr()); err != nil {
+		t.Fatalf("WriteTo failed with error: %v", err)
+	}
+
+	buf := make([]byte, 1500)
+	if _, _, err := conn.ReadFrom(buf); err != nil {
+		t.Errorf("ReadFrom failed with error: %v", err)
+	}
+}
@@ -7,9 +7,50 @@ import (
 // Packet represents an SAP Packet
 type Packet struct {
 	Header
+
+	// Key id, timeout and Cipher of an encrypted packet.
+	// It MUST be set to marshal a packet with the encrypted bit set.
+	Encryption *Encryption
+
+	// Signs the packet when it is marshaled, filling in the authentication length and data.
+	Signer Signer
+
+	// How strictly the header is checked when the packet is marshaled, Lenient by default
+	EncodePolicy Policy
+
+	// Computes the message id hash when the packet is marshaled with a zero hash, CRCHash by default
+	HashStrategy HashStrategy
+
 	Payload []byte
 }
 
+// UnmarshalOption configures how Packet.Unmarshal and Header.Unmarshal decode a packet.
+type UnmarshalOption func(*unmarshalConfig)
+
+type unmarshalConfig struct {
+	keyring           Keyring
+	verifiers         []Verifier
+	policy            Policy
+	payloadTypePolicy PayloadTypePolicy
+}
+
+// newUnmarshalConfig returns the configuration set by opts.
+func newUnmarshalConfig(opts []UnmarshalOption) unmarshalConfig {
+	config := unmarshalConfig{payloadTypePolicy: DefaultPayloadTypePolicy}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
+
+// Decrypt encrypted packets with the keys held by the Keyring
+func WithKeyring(keyring Keyring) UnmarshalOption {
+	return func(c *unmarshalConfig) {
+		c.keyring = keyring
+	}
+}
+
 // String helps with debugging by printing packet information in a readable way
 func (p Packet) String() string {
 	out := "SAP PACKET:\n"
@@ -24,7 +65,13 @@ func (p Packet) String() string {
 	out += fmt.Sprintf("\tAuthenticationData: %d\n", p.AuthenticationData)
 	out += fmt.Sprintf("\tMessageIDHash: %d\n", p.MessageIDHash)
 	out += fmt.Sprintf("\tOriginatingSource: %d\n", p.OriginatingSource)
+	if p.Encryption != nil {
+		out += fmt.Sprintf("\tKeyID: %d\n", p.Encryption.KeyID)
+		out += fmt.Sprintf("\tTimeout: %v\n", p.Encryption.Timeout)
+		out += fmt.Sprintf("\tUndecryptable: %t\n", p.Undecryptable())
+	}
 	out += fmt.Sprintf("\tPayload Type: %s\n", p.PayloadType)
+	out += fmt.Sprintf("\tPayload Type Source: %v\n", p.PayloadTypeSource)
 	out += fmt.Sprintf("\tPayload Length: %d\n", len(p.Payload))
 
 	return out
@@ -32,27 +79,58 @@ func (p Packet) String() string {
 
 // Marshal serializes the packet into bytes.
 func (p Packet) Marshal() (buf []byte, err error) {
-	buf = make([]byte, p.MarshalSize())
-
-	n, err := p.MarshalTo(buf)
+	prepared, err := p.Prepare()
 	if err != nil {
 		return nil, err
 	}
 
-	return buf[:n], nil
+	return prepared.data, nil
 }
 
 // MarshalTo serializes the packet and writes to the buffer.
 // It returns the number of bytes read and any error.
 func (p Packet) MarshalTo(buf []byte) (n int, err error) {
-	headerSize := p.Header.MarshalSize()
-	if len(buf) < headerSize {
-		return 0, errBufTooSmallForHeader
+	p, body, err := p.prepare()
+	if err != nil {
+		return 0, err
 	}
 
+	return p.marshalTo(buf, body)
+}
+
+// prepare returns a copy of the packet ready to be written, along with the bytes following its header.
+// It fills in the message id hash if missing and signs the packet if it has a Signer.
+func (p Packet) prepare() (Packet, []byte, error) {
 	// Add the hash to the Header if it doesn't have one
 	if p.Header.MessageIDHash == 0 {
-		p.Header.MessageIDHash = ComputeMsgIdHash(p.Payload)
+		p.Header.MessageIDHash = p.messageIDHash()
+	}
+
+	body, err := p.body()
+	if err != nil {
+		return p, nil, err
+	}
+
+	if p.Signer != nil {
+		if err := p.sign(body); err != nil {
+			return p, nil, err
+		}
+	}
+
+	if p.EncodePolicy == Strict {
+		if err := p.Header.Validate(); err != nil {
+			return p, nil, err
+		}
+	}
+
+	return p, body, nil
+}
+
+// marshalTo writes the header followed by body, the bytes returned by p.body().
+func (p Packet) marshalTo(buf []byte, body []byte) (n int, err error) {
+	headerSize := p.Header.MarshalSize()
+	if len(buf) < headerSize {
+		return 0, errBufTooSmallForHeader
 	}
 
 	n, err = p.Header.MarshalTo(buf)
@@ -61,42 +139,216 @@ func (p Packet) MarshalTo(buf []byte) (n int, err error) {
 	}
 
 	// Make sure the buffer is large enough to hold the packet.
-	if len(buf) < headerSize+len(p.Payload) {
+	if len(buf) < headerSize+len(body) {
 		return 0, errBufTooSmallForPayload
 	}
 
-	payloadSize := copy(buf[n:], p.Payload)
+	payloadSize := copy(buf[n:], body)
 
 	return headerSize + payloadSize, nil
 }
 
-// MarshalSize returns the size of the packet once marshaled.
+// body returns what follows the header on the wire.
+// That is the payload as is, or when the compressed or encrypted bit is set,
+// the payload type and the payload, first compressed (RFC 2974 section 5) and then encrypted.
+func (p Packet) body() ([]byte, error) {
+	if p.payloadTypeInHeader() {
+		return p.Payload, nil
+	}
+
+	var data []byte
+	if p.PayloadType != "" && p.Version != 0 {
+		data = make([]byte, 0, len(p.PayloadType)+1+len(p.Payload))
+		data = append(data, p.PayloadType...)
+
+		// Trailing zero after the Payload Type
+		data = append(data, 0)
+	}
+	data = append(data, p.Payload...)
+
+	if p.Compressed == 1 {
+		var err error
+		data, err = compress(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Encrypted == 1 {
+		return p.Encryption.encrypt(data)
+	}
+
+	return data, nil
+}
+
+// MarshalSize returns the size of the packet once marshaled, or 0 if it cannot be marshaled, Marshal then tells why.
+// The packet is not signed to be sized, so the size of a packet with a Signer leaves out its signature,
+// which is only known once signed: Prepare signs the packet once and sizes it.
 func (p Packet) MarshalSize() int {
-	return p.Header.MarshalSize() + len(p.Payload)
+	body, err := p.body()
+	if err != nil {
+		return 0
+	}
+
+	return p.Header.MarshalSize() + len(body)
+}
+
+// PreparedPacket is a packet marshaled once: its message id hash is filled in,
+// it is compressed, encrypted and signed. Its size does not change from one MarshalTo to the next.
+type PreparedPacket struct {
+	// The packet as it is sent, with the authentication data of its signature
+	Packet Packet
+
+	data []byte
+}
+
+// Prepare marshals the packet once, so that it can be sized and written without being signed
+// and encrypted again. A buffer of MarshalSize bytes always holds the prepared packet.
+func (p Packet) Prepare() (*PreparedPacket, error) {
+	p, body, err := p.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, p.Header.MarshalSize()+len(body))
+
+	n, err := p.marshalTo(buf, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreparedPacket{Packet: p, data: buf[:n]}, nil
+}
+
+// MarshalSize returns the size of the prepared packet.
+func (p *PreparedPacket) MarshalSize() int {
+	return len(p.data)
+}
+
+// MarshalTo writes the prepared packet to the buffer.
+// It returns the number of bytes written and any error.
+func (p *PreparedPacket) MarshalTo(buf []byte) (n int, err error) {
+	if len(buf) < len(p.data) {
+		return 0, errBufTooSmallForPayload
+	}
+
+	return copy(buf, p.data), nil
+}
+
+// Marshal returns a copy of the prepared packet.
+func (p *PreparedPacket) Marshal() ([]byte, error) {
+	return append([]byte(nil), p.data...), nil
 }
 
 // Unmarshal parses the passed byte slice and stores the result in the Packet.
-func (p *Packet) Unmarshal(buf []byte) error {
-	err := p.Header.Unmarshal(buf)
+// When verifiers are passed using WithVerifiers, packets without a valid signature are rejected.
+// Encrypted payloads are decrypted with the Keyring passed using WithKeyring and
+// compressed payloads are inflated. The payload type is then recovered from the result.
+//
+// A packet encrypted with a key that is not held is not an error. Its header and Encryption
+// are filled in, the ciphertext is kept in Payload and Undecryptable reports true.
+func (p *Packet) Unmarshal(buf []byte, opts ...UnmarshalOption) error {
+	config := newUnmarshalConfig(opts)
+
+	headerSize, err := p.Header.unmarshal(buf, config.policy, config.payloadTypePolicy)
 	if err != nil {
 		return err
 	}
 
-	headerSize := p.Header.MarshalSize()
+	if len(config.verifiers) > 0 {
+		if err := verify(buf, p.Header, config.verifiers); err != nil {
+			return err
+		}
+	}
+
+	p.Encryption = nil
+
 	end := len(buf)
-	if len(buf) > headerSize { // only slice if needed
-		p.Payload = buf[headerSize:end]
-	} else {
+	if len(buf) <= headerSize {
+		if p.Encrypted == 1 {
+			return truncated(ErrBufTooSmallForEncryption, "Key Id", buf, headerSize, encryptionHeaderSize)
+		}
+
 		// no payload
 		return nil
 	}
+
+	// only slice if needed
+	p.Payload = buf[headerSize:end]
+
+	if p.payloadTypeInHeader() {
+		return nil
+	}
+
+	data := p.Payload
+
+	if p.Encrypted == 1 {
+		encryption, ciphertext, err := unmarshalEncryption(data)
+		if err != nil {
+			return truncated(err, "Key Id", buf, headerSize, encryptionHeaderSize)
+		}
+		p.Encryption = encryption
+		p.Payload = ciphertext
+
+		if config.keyring == nil {
+			return nil
+		}
+
+		c, ok := config.keyring.Cipher(encryption.KeyID)
+		if !ok {
+			return nil
+		}
+		encryption.Cipher = c
+
+		data, err = encryption.decrypt(ciphertext)
+		if err != nil {
+			return malformed(err, "Payload", headerSize+encryptionHeaderSize)
+		}
+	}
+
+	if p.Compressed == 1 {
+		data, err = decompress(data)
+		if err != nil {
+			return malformed(err, "Payload", len(buf)-len(p.Payload))
+		}
+	}
+
+	if p.Version == 0 {
+		// SAPv0 packets have no payload type
+		p.Payload = data
+		return nil
+	}
+
+	payloadType, n, source, err := config.payloadTypePolicy.detect(data)
+	if err != nil {
+		// The offset is that of the payload, the payload type is compressed or encrypted with it
+		return malformed(err, "Payload Type", len(buf)-len(p.Payload))
+	}
+
+	p.PayloadType = payloadType
+	p.PayloadTypeSource = source
+	p.Payload = data[n:]
+
 	return nil
 }
 
+// Undecryptable reports whether the packet is encrypted with a key that was not
+// available when it was unmarshaled. Its Payload then holds the ciphertext.
+func (p Packet) Undecryptable() bool {
+	return p.Encrypted == 1 && p.Encryption != nil && p.Encryption.Cipher == nil
+}
+
 // Clone returns a deep copy of p.
 func (p Packet) Clone() *Packet {
 	clone := &Packet{}
 	clone.Header = p.Header.Clone()
+	if p.Encryption != nil {
+		encryption := *p.Encryption
+		clone.Encryption = &encryption
+	}
+	clone.Signer = p.Signer
+	clone.EncodePolicy = p.EncodePolicy
+	clone.HashStrategy = p.HashStrategy
 	if p.Payload != nil {
 		clone.Payload = make([]byte, len(p.Payload))
 		copy(clone.Payload, p.Payload)
@@ -1,6 +1,8 @@
 package sap
 
 import (
+	"errors"
+	"net/netip"
 	"reflect"
 	"testing"
 )
@@ -24,6 +26,14 @@ func TestPacketMarshalSize(t *testing.T) {
 			}),
 			want: 10,
 		},
+		{
+			name: "Test 3: Encrypted Without Cipher",
+			mockPacket: CreateMockPacket(Packet{
+				Header:  Header{Encrypted: 1},
+				Payload: []byte{0x10, 0x04},
+			}),
+			want: 0,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -45,7 +55,7 @@ func TestPacketUnmarshalErrors(t *testing.T) {
 		{
 			name:          "BufTooSmallForFlags",
 			input:         make([]byte, 0), // Not enough bytes to unmarshal flags.
-			expectedError: errBufTooSmallForFlags,
+			expectedError: ErrBufTooSmallForFlags,
 		},
 	}
 
@@ -53,7 +63,7 @@ func TestPacketUnmarshalErrors(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockPacket := CreateMockPacket(Packet{})
 			err := mockPacket.Unmarshal(tc.input)
-			if err != tc.expectedError {
+			if !errors.Is(err, tc.expectedError) {
 				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
 			}
 		})
@@ -158,7 +168,7 @@ func TestHeaderMarshalToErrors(t *testing.T) {
 				Payload: []byte{0x10, 0x04},
 			})
 			_, err := mockPacket.MarshalTo(tc.input)
-			if err != tc.expectedError {
+			if !errors.Is(err, tc.expectedError) {
 				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
 			}
 		})
@@ -186,3 +196,28 @@ func CreateMockPacket(p Packet) *Packet {
 
 	return &Packet{Header: CreateMockHeader(p.Header), Payload: newPayload}
 }
+
+func TestNewPacketFromAddr(t *testing.T) {
+	p, err := NewPacketFromAddr([]byte("v=0\r\n"), netip.MustParseAddr("2001:db8::68"), WithCompression())
+	if err != nil {
+		t.Fatalf("NewPacketFromAddr failed with error: %v", err)
+	}
+
+	if p.AddressType != IPv6 || p.Compressed != 1 {
+		t.Errorf("unexpected address type %v and compressed bit %d", p.AddressType, p.Compressed)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	v, err := ViewPacket(data)
+	if err != nil {
+		t.Fatalf("ViewPacket failed with error: %v", err)
+	}
+
+	if v.OriginatingSource() != p.Source() || p.AnnouncementKey().Source != v.OriginatingSource() {
+		t.Errorf("expected source %v, got %v", p.Source(), v.OriginatingSource())
+	}
+}
@@ -0,0 +1,164 @@
+package sap
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// SDPPayloadType is the payload type of SDP session descriptions, implied when the payload type is omitted
+const SDPPayloadType = "application/sdp"
+
+// PayloadTypeSource records how the payload type of an unmarshaled packet was found
+type PayloadTypeSource uint8
+
+const (
+	// The payload type was not looked for, as there is no payload or it could not be decrypted
+	PayloadTypeUnknown PayloadTypeSource = iota
+
+	// The payload type was sent, followed by a zero byte
+	PayloadTypeExplicit
+
+	// The payload type was omitted and the payload is an SDP description, as RFC 2974 allows
+	PayloadTypeImplicit
+
+	// The payload type was recovered by tolerating a quirk of the announcer, see PayloadTypePolicy
+	PayloadTypeQuirk
+)
+
+func (s PayloadTypeSource) String() string {
+	switch s {
+	case PayloadTypeUnknown:
+		return "unknown"
+	case PayloadTypeExplicit:
+		return "explicit"
+	case PayloadTypeImplicit:
+		return "implicit"
+	case PayloadTypeQuirk:
+		return "quirk"
+	default:
+		return fmt.Sprintf("PayloadTypeSource(%d)", uint8(s))
+	}
+}
+
+// utf8BOM is the byte order mark some announcers put in front of the SDP description
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// PayloadTypePolicy decides how the payload type is told apart from the payload.
+//
+// The payload type is detected in this order:
+//  1. A payload starting with "v=0" is an SDP description with an omitted payload type.
+//  2. The text up to the first zero byte is the payload type, if it is a known or valid media type.
+//  3. With TolerateQuirks, an SDP description preceded by a byte order mark or whitespace
+//     is taken as an SDP description with an omitted payload type, and a known payload type
+//     terminated by a line break instead of a zero byte is accepted.
+//
+// Anything else is an error.
+type PayloadTypePolicy struct {
+	// Media types accepted as payload types besides application/sdp, compared without case and parameters
+	KnownTypes []string
+
+	// Reject payload types that are valid media types but not known
+	OnlyKnownTypes bool
+
+	// Accept the common deviations of announcers listed above, and report them with PayloadTypeQuirk
+	TolerateQuirks bool
+}
+
+// DefaultPayloadTypePolicy accepts any valid media type and tolerates the common quirks.
+// It is used by Header.Unmarshal and by Packet.Unmarshal unless WithPayloadTypePolicy is passed.
+var DefaultPayloadTypePolicy = PayloadTypePolicy{TolerateQuirks: true}
+
+// Detect the payload type with the given policy instead of DefaultPayloadTypePolicy
+func WithPayloadTypePolicy(policy PayloadTypePolicy) UnmarshalOption {
+	return func(c *unmarshalConfig) {
+		c.payloadTypePolicy = policy
+	}
+}
+
+// detect reads the optional payload type at the start of buf.
+// It returns the payload type, the number of bytes it takes up including its terminator and how it was found.
+// An empty payload type means the payload type was omitted and the payload is application/sdp.
+func (pol PayloadTypePolicy) detect(buf []byte) (payloadType string, n int, source PayloadTypeSource, err error) {
+	if len(buf) < 3 {
+		// no payload
+		return "", 0, PayloadTypeUnknown, nil
+	}
+
+	if isSDP(buf) {
+		return "", 0, PayloadTypeImplicit, nil
+	}
+
+	i := bytes.IndexByte(buf, 0)
+	if i >= 0 {
+		payloadType, err = pol.mediaType(buf[:i])
+		if err == nil {
+			return payloadType, i + 1, PayloadTypeExplicit, nil
+		}
+	} else {
+		err = ErrNoTrailingByteFound
+	}
+
+	if pol.TolerateQuirks {
+		if isSDP(trimSDPQuirks(buf)) {
+			return "", 0, PayloadTypeQuirk, nil
+		}
+
+		if j := bytes.IndexByte(buf, '\n'); j >= 0 && (i < 0 || j < i) {
+			if payloadType, ok := pol.known(bytes.TrimRight(buf[:j], "\r")); ok {
+				return payloadType, j + 1, PayloadTypeQuirk, nil
+			}
+		}
+	}
+
+	return "", 0, PayloadTypeUnknown, err
+}
+
+// mediaType parses the payload type b, checking it against the known types.
+func (pol PayloadTypePolicy) mediaType(b []byte) (string, error) {
+	if payloadType, ok := pol.known(b); ok {
+		return payloadType, nil
+	}
+
+	payloadType, _, err := mime.ParseMediaType(string(b))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPayloadType, err)
+	}
+
+	if pol.OnlyKnownTypes {
+		return "", ErrUnknownPayloadType
+	}
+
+	return payloadType, nil
+}
+
+// known returns the known media type b is, ignoring case, surrounding spaces and parameters.
+func (pol PayloadTypePolicy) known(b []byte) (string, bool) {
+	if i := bytes.IndexByte(b, ';'); i >= 0 {
+		b = b[:i]
+	}
+	b = bytes.TrimSpace(b)
+
+	if bytes.EqualFold(b, []byte(SDPPayloadType)) {
+		return SDPPayloadType, true
+	}
+
+	for _, knownType := range pol.KnownTypes {
+		if bytes.EqualFold(b, []byte(knownType)) {
+			return strings.ToLower(knownType), true
+		}
+	}
+
+	return "", false
+}
+
+// trimSDPQuirks removes the byte order mark and the whitespace some announcers put in front of an SDP description.
+func trimSDPQuirks(buf []byte) []byte {
+	return bytes.TrimLeft(bytes.TrimPrefix(buf, utf8BOM), " \t\r\n")
+}
+
+// isSDP reports whether buf starts with the version line of an SDP description.
+func isSDP(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte("v=0"))
+}
@@ -0,0 +1,150 @@
+package sap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPayloadTypePolicyDetect(t *testing.T) {
+	testCases := []struct {
+		name          string
+		policy        PayloadTypePolicy
+		input         string
+		payloadType   string
+		n             int
+		source        PayloadTypeSource
+		expectedError error
+	}{
+		{
+			name:   "No Payload",
+			policy: DefaultPayloadTypePolicy,
+			input:  "",
+			source: PayloadTypeUnknown,
+		},
+		{
+			name:   "Implicit SDP",
+			policy: DefaultPayloadTypePolicy,
+			input:  "v=0\r\n",
+			source: PayloadTypeImplicit,
+		},
+		{
+			name:        "Explicit SDP",
+			policy:      DefaultPayloadTypePolicy,
+			input:       "application/sdp\x00v=0\r\n",
+			payloadType: "application/sdp",
+			n:           16,
+			source:      PayloadTypeExplicit,
+		},
+		{
+			name:        "Explicit Upper Case With Parameters",
+			policy:      DefaultPayloadTypePolicy,
+			input:       "Application/SDP; charset=utf-8\x00v=0\r\n",
+			payloadType: "application/sdp",
+			n:           31,
+			source:      PayloadTypeExplicit,
+		},
+		{
+			name:        "Explicit Other Type",
+			policy:      DefaultPayloadTypePolicy,
+			input:       "application/json\x00{}",
+			payloadType: "application/json",
+			n:           17,
+			source:      PayloadTypeExplicit,
+		},
+		{
+			name:   "SDP With Byte Order Mark",
+			policy: DefaultPayloadTypePolicy,
+			input:  "\xEF\xBB\xBFv=0\r\n",
+			source: PayloadTypeQuirk,
+		},
+		{
+			name:   "SDP With Leading Whitespace",
+			policy: DefaultPayloadTypePolicy,
+			input:  "\r\n v=0\r\n",
+			source: PayloadTypeQuirk,
+		},
+		{
+			name:          "SDP With Byte Order Mark Without Quirks",
+			policy:        PayloadTypePolicy{},
+			input:         "\xEF\xBB\xBFv=0\r\n",
+			expectedError: ErrNoTrailingByteFound,
+		},
+		{
+			name:        "Payload Type Terminated By Line Break",
+			policy:      DefaultPayloadTypePolicy,
+			input:       "application/sdp\r\nv=0\r\n",
+			payloadType: "application/sdp",
+			n:           17,
+			source:      PayloadTypeQuirk,
+		},
+		{
+			name:        "Plugged In Type Terminated By Line Break",
+			policy:      PayloadTypePolicy{KnownTypes: []string{"application/x-sap-test"}, TolerateQuirks: true},
+			input:       "application/x-sap-test\n{}",
+			payloadType: "application/x-sap-test",
+			n:           23,
+			source:      PayloadTypeQuirk,
+		},
+		{
+			name:          "Only Known Types",
+			policy:        PayloadTypePolicy{OnlyKnownTypes: true},
+			input:         "application/json\x00{}",
+			expectedError: ErrUnknownPayloadType,
+		},
+		{
+			name:        "Only Known Types With Plugged In Type",
+			policy:      PayloadTypePolicy{KnownTypes: []string{"application/json"}, OnlyKnownTypes: true},
+			input:       "application/json\x00{}",
+			payloadType: "application/json",
+			n:           17,
+			source:      PayloadTypeExplicit,
+		},
+		{
+			name:          "Invalid Media Type",
+			policy:        DefaultPayloadTypePolicy,
+			input:         "not a type\x00",
+			expectedError: ErrInvalidPayloadType,
+		},
+		{
+			name:          "Other Text",
+			policy:        DefaultPayloadTypePolicy,
+			input:         "hello",
+			expectedError: ErrNoTrailingByteFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payloadType, n, source, err := tc.policy.detect([]byte(tc.input))
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+
+			if payloadType != tc.payloadType || n != tc.n || source != tc.source {
+				t.Errorf("expected %q, %d, %v, got %q, %d, %v", tc.payloadType, tc.n, tc.source, payloadType, n, source)
+			}
+		})
+	}
+}
+
+func TestPacketUnmarshalPayloadTypePolicy(t *testing.T) {
+	header, err := CreateMockHeader(Header{}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	data := append(header, "application/sdp\r\nv=0\r\n"...)
+
+	p := Packet{}
+	if err := p.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	if p.PayloadType != "application/sdp" || p.PayloadTypeSource != PayloadTypeQuirk || string(p.Payload) != "v=0\r\n" {
+		t.Errorf("unexpected payload type %q from %v and payload %q", p.PayloadType, p.PayloadTypeSource, p.Payload)
+	}
+
+	if err := p.Unmarshal(data, WithPayloadTypePolicy(PayloadTypePolicy{})); !errors.Is(err, ErrNoTrailingByteFound) {
+		t.Errorf("Expected error %v, but got %v", ErrNoTrailingByteFound, err)
+	}
+}
@@ -0,0 +1,198 @@
+// Package pcap reads SAP packets out of pcap and pcapng captures and writes synthetic captures, without libpcap.
+//
+// Only UDP datagrams sent to the SAP port over IPv4 or IPv6 are read, captured on Ethernet,
+// Linux cooked, BSD loopback or raw IP links. Fragmented datagrams are skipped.
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+)
+
+var (
+	errUnknownFormat      = errors.New("pcap: not a pcap or pcapng capture")
+	errUnsupportedVersion = errors.New("pcap: unsupported capture format version")
+	errTruncatedCapture   = errors.New("pcap: capture is truncated")
+	errInvalidBlock       = errors.New("pcap: invalid pcapng block")
+	errUnknownInterface   = errors.New("pcap: packet refers to an unknown pcapng interface")
+	errAddressFamily      = errors.New("pcap: source and destination are not of the same address family")
+	errDatagramTooLarge   = errors.New("pcap: datagram does not fit in a UDP packet")
+	errZeroTimestamp      = errors.New("pcap: record has no timestamp")
+	errTimestampRange     = errors.New("pcap: timestamp is before 1970 or after 2106")
+)
+
+// Link types of the frames, as registered at https://www.tcpdump.org/linktypes.html
+const (
+	LinkTypeNull     uint16 = 0
+	LinkTypeEthernet uint16 = 1
+	LinkTypeRaw      uint16 = 101
+	LinkTypeLinuxSLL uint16 = 113
+	LinkTypeIPv4     uint16 = 228
+	LinkTypeIPv6     uint16 = 229
+)
+
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86DD
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88A8
+	protocolUDP    = 17
+	udpHeaderSize  = 8
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+)
+
+// Record is a SAP datagram of a capture.
+type Record struct {
+	// When the datagram was captured
+	Timestamp time.Time
+
+	// The addresses and ports the datagram was sent from and to
+	Source      netip.AddrPort
+	Destination netip.AddrPort
+
+	// The UDP payload, which is the SAP packet
+	Data []byte
+}
+
+// Unmarshal decodes the SAP packet of the record with sap.Packet.Unmarshal.
+func (r Record) Unmarshal(opts ...sap.UnmarshalOption) (*sap.Packet, error) {
+	p := &sap.Packet{}
+	if err := p.Unmarshal(r.Data, opts...); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// decodeFrame returns the SAP datagram carried by a frame of the link type, if it carries one.
+func decodeFrame(linkType uint16, frame []byte) (Record, bool) {
+	switch linkType {
+	case LinkTypeEthernet:
+		if len(frame) < 14 {
+			return Record{}, false
+		}
+		etherType := binary.BigEndian.Uint16(frame[12:14])
+		frame = frame[14:]
+
+		// Skip the VLAN tags
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			if len(frame) < 4 {
+				return Record{}, false
+			}
+			etherType = binary.BigEndian.Uint16(frame[2:4])
+			frame = frame[4:]
+		}
+
+		return decodeIP(etherType, frame)
+	case LinkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return Record{}, false
+		}
+		return decodeIP(binary.BigEndian.Uint16(frame[14:16]), frame[16:])
+	case LinkTypeNull:
+		if len(frame) < 4 {
+			return Record{}, false
+		}
+		// The address family is in the byte order of the capturing host, and its value for IPv6 depends on the OS
+		family := binary.LittleEndian.Uint32(frame[:4])
+		if family > 0xFFFF {
+			family = binary.BigEndian.Uint32(frame[:4])
+		}
+		if family == 2 {
+			return decodeIP(etherTypeIPv4, frame[4:])
+		}
+		return decodeIP(etherTypeIPv6, frame[4:])
+	case LinkTypeRaw, LinkTypeIPv4, LinkTypeIPv6:
+		if len(frame) == 0 {
+			return Record{}, false
+		}
+		if frame[0]>>4 == 4 {
+			return decodeIP(etherTypeIPv4, frame)
+		}
+		return decodeIP(etherTypeIPv6, frame)
+	default:
+		return Record{}, false
+	}
+}
+
+// decodeIP returns the SAP datagram carried by an IPv4 or IPv6 packet.
+func decodeIP(etherType uint16, packet []byte) (Record, bool) {
+	var src, dst netip.Addr
+	var payload []byte
+
+	switch etherType {
+	case etherTypeIPv4:
+		if len(packet) < ipv4HeaderSize || packet[0]>>4 != 4 {
+			return Record{}, false
+		}
+
+		headerSize := int(packet[0]&0x0F) * 4
+		totalSize := int(binary.BigEndian.Uint16(packet[2:4]))
+		if headerSize < ipv4HeaderSize || totalSize < headerSize || len(packet) < totalSize {
+			return Record{}, false
+		}
+
+		// More fragments flag or fragment offset
+		if binary.BigEndian.Uint16(packet[6:8])&0x3FFF != 0 || packet[9] != protocolUDP {
+			return Record{}, false
+		}
+
+		src = netip.AddrFrom4([4]byte(packet[12:16]))
+		dst = netip.AddrFrom4([4]byte(packet[16:20]))
+		payload = packet[headerSize:totalSize]
+	case etherTypeIPv6:
+		if len(packet) < ipv6HeaderSize || packet[0]>>4 != 6 {
+			return Record{}, false
+		}
+
+		payloadSize := int(binary.BigEndian.Uint16(packet[4:6]))
+		if len(packet) < ipv6HeaderSize+payloadSize {
+			return Record{}, false
+		}
+
+		src = netip.AddrFrom16([16]byte(packet[8:24]))
+		dst = netip.AddrFrom16([16]byte(packet[24:40]))
+		payload = packet[ipv6HeaderSize : ipv6HeaderSize+payloadSize]
+
+		// Skip the hop-by-hop, routing and destination options extension headers
+		nextHeader := packet[6]
+		for nextHeader == 0 || nextHeader == 43 || nextHeader == 60 {
+			if len(payload) < 8 {
+				return Record{}, false
+			}
+			size := (int(payload[1]) + 1) * 8
+			if len(payload) < size {
+				return Record{}, false
+			}
+			nextHeader = payload[0]
+			payload = payload[size:]
+		}
+
+		if nextHeader != protocolUDP {
+			return Record{}, false
+		}
+	default:
+		return Record{}, false
+	}
+
+	if len(payload) < udpHeaderSize {
+		return Record{}, false
+	}
+
+	dstPort := binary.BigEndian.Uint16(payload[2:4])
+	udpSize := int(binary.BigEndian.Uint16(payload[4:6]))
+	if dstPort != sap.Port || udpSize < udpHeaderSize || len(payload) < udpSize {
+		return Record{}, false
+	}
+
+	return Record{
+		Source:      netip.AddrPortFrom(src, binary.BigEndian.Uint16(payload[0:2])),
+		Destination: netip.AddrPortFrom(dst, dstPort),
+		Data:        payload[udpHeaderSize:udpSize],
+	}, true
+}
@@ -0,0 +1,273 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+)
+
+func createTestPacket(t *testing.T, source string) []byte {
+	t.Helper()
+
+	p, err := sap.NewPacket([]byte("v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=Test\r\nt=0 0\r\n"), net.UDPAddr{IP: net.ParseIP(source)})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	return data
+}
+
+func TestWriterAndReader(t *testing.T) {
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+
+	records := []Record{
+		{
+			Timestamp:   timestamp,
+			Source:      netip.MustParseAddrPort("192.0.2.1:40000"),
+			Destination: netip.MustParseAddrPort("239.255.255.255:9875"),
+			Data:        createTestPacket(t, "192.0.2.1"),
+		},
+		{
+			// Not a SAP datagram, skipped when reading
+			Timestamp:   timestamp.Add(time.Second),
+			Source:      netip.MustParseAddrPort("192.0.2.1:40000"),
+			Destination: netip.MustParseAddrPort("239.69.1.1:5004"),
+			Data:        []byte{0x80, 0x61},
+		},
+		{
+			Timestamp:   timestamp.Add(2 * time.Second),
+			Source:      netip.MustParseAddrPort("[2001:db8::68]:40000"),
+			Destination: netip.MustParseAddrPort("[ff0e::2:7ffe]:9875"),
+			Data:        createTestPacket(t, "2001:db8::68"),
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		format Format
+	}{
+		{name: "Pcap", format: FormatPcap},
+		{name: "PcapNG", format: FormatPcapNG},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			w, err := NewWriter(buf, tc.format)
+			if err != nil {
+				t.Fatalf("NewWriter failed with error: %v", err)
+			}
+			for _, record := range records {
+				if err := w.Write(record); err != nil {
+					t.Fatalf("Write failed with error: %v", err)
+				}
+			}
+
+			r, err := NewReader(buf)
+			if err != nil {
+				t.Fatalf("NewReader failed with error: %v", err)
+			}
+
+			for _, want := range []Record{records[0], records[2]} {
+				got, err := r.Next()
+				if err != nil {
+					t.Fatalf("Next failed with error: %v", err)
+				}
+
+				if !got.Timestamp.Equal(want.Timestamp) || got.Source != want.Source || got.Destination != want.Destination || !bytes.Equal(got.Data, want.Data) {
+					t.Errorf("expected %v, got %v", want, got)
+				}
+
+				p, err := got.Unmarshal()
+				if err != nil {
+					t.Fatalf("Unmarshal failed with error: %v", err)
+				}
+				if p.Source() != want.Source.Addr() {
+					t.Errorf("expected source %v, got %v", want.Source.Addr(), p.Source())
+				}
+			}
+
+			if _, err := r.Next(); err != io.EOF {
+				t.Errorf("Expected error %v, but got %v", io.EOF, err)
+			}
+		})
+	}
+}
+
+// TestReaderBigEndianRaw reads a big endian pcap capture with microsecond timestamps of raw IPv4 packets.
+func TestReaderBigEndianRaw(t *testing.T) {
+	frame, err := encodeFrame(Record{
+		Source:      netip.MustParseAddrPort("192.0.2.1:9875"),
+		Destination: netip.MustParseAddrPort("224.2.127.254:9875"),
+		Data:        createTestPacket(t, "192.0.2.1"),
+	})
+	if err != nil {
+		t.Fatalf("encodeFrame failed with error: %v", err)
+	}
+	frame = frame[14:]
+
+	var capture []byte
+	order := binary.BigEndian
+	capture = order.AppendUint32(capture, magicMicroseconds)
+	capture = order.AppendUint16(capture, 2)
+	capture = order.AppendUint16(capture, 4)
+	capture = order.AppendUint64(capture, 0)
+	capture = order.AppendUint32(capture, snapLength)
+	capture = order.AppendUint32(capture, uint32(LinkTypeRaw))
+	capture = order.AppendUint32(capture, 1700000000)
+	capture = order.AppendUint32(capture, 250000)
+	capture = order.AppendUint32(capture, uint32(len(frame)))
+	capture = order.AppendUint32(capture, uint32(len(frame)))
+	capture = append(capture, frame...)
+
+	r, err := NewReader(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatalf("NewReader failed with error: %v", err)
+	}
+
+	got, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next failed with error: %v", err)
+	}
+
+	if want := time.Unix(1700000000, 250000000); !got.Timestamp.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got.Timestamp)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		capture       []byte
+		expectedError error
+	}{
+		{
+			name:          "Empty",
+			capture:       []byte{},
+			expectedError: errUnknownFormat,
+		},
+		{
+			name:          "UnknownMagic",
+			capture:       make([]byte, 24),
+			expectedError: errUnknownFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tc.capture))
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+
+	// A packet cut short by the end of the capture
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, FormatPcap)
+	if err != nil {
+		t.Fatalf("NewWriter failed with error: %v", err)
+	}
+	if err := w.Write(Record{
+		Timestamp:   time.Unix(1700000000, 0),
+		Source:      netip.MustParseAddrPort("192.0.2.1:9875"),
+		Destination: netip.MustParseAddrPort("224.2.127.254:9875"),
+		Data:        createTestPacket(t, "192.0.2.1"),
+	}); err != nil {
+		t.Fatalf("Write failed with error: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if err != nil {
+		t.Fatalf("NewReader failed with error: %v", err)
+	}
+	if _, err := r.Next(); !errors.Is(err, errTruncatedCapture) {
+		t.Errorf("Expected error %v, but got %v", errTruncatedCapture, err)
+	}
+}
+
+func TestWriterTimestampErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		timestamp     time.Time
+		expectedError error
+	}{
+		{
+			name:          "ZeroTimestamp",
+			expectedError: errZeroTimestamp,
+		},
+		{
+			name:          "Before1970",
+			timestamp:     time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+			expectedError: errTimestampRange,
+		},
+		{
+			name:          "After2106",
+			timestamp:     time.Date(2106, 2, 8, 0, 0, 0, 0, time.UTC),
+			expectedError: errTimestampRange,
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, format := range []Format{FormatPcap, FormatPcapNG} {
+			t.Run(tc.name, func(t *testing.T) {
+				w, err := NewWriter(io.Discard, format)
+				if err != nil {
+					t.Fatalf("NewWriter failed with error: %v", err)
+				}
+
+				err = w.Write(Record{
+					Timestamp:   tc.timestamp,
+					Source:      netip.MustParseAddrPort("192.0.2.1:9875"),
+					Destination: netip.MustParseAddrPort("224.2.127.254:9875"),
+					Data:        createTestPacket(t, "192.0.2.1"),
+				})
+				if !errors.Is(err, tc.expectedError) {
+					t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeFrameChecksums(t *testing.T) {
+	src := netip.MustParseAddrPort("192.0.2.1:40000")
+	dst := netip.MustParseAddrPort("239.255.255.255:9875")
+
+	frame, err := encodeFrame(Record{Source: src, Destination: dst, Data: []byte("odd")})
+	if err != nil {
+		t.Fatalf("encodeFrame failed with error: %v", err)
+	}
+
+	if !bytes.Equal(frame[:6], []byte{0x01, 0x00, 0x5E, 0x7F, 0xFF, 0xFF}) {
+		t.Errorf("unexpected destination MAC % x", frame[:6])
+	}
+
+	ip, udp := frame[14:34], frame[34:]
+	if got := checksum(0, ip); got != 0xFFFF {
+		t.Errorf("invalid IPv4 header checksum, sum is %#x", got)
+	}
+
+	sum := checksum(0, ip[12:20])
+	sum = checksum(sum, []byte{0, protocolUDP, 0, byte(len(udp))})
+	if got := checksum(sum, udp); got != 0xFFFF {
+		t.Errorf("invalid UDP checksum, sum is %#x", got)
+	}
+
+	if _, err := encodeFrame(Record{Source: src, Destination: netip.MustParseAddrPort("[ff0e::2:7ffe]:9875")}); !errors.Is(err, errAddressFamily) {
+		t.Errorf("Expected error %v, but got %v", errAddressFamily, err)
+	}
+}
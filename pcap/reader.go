@@ -0,0 +1,296 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	magicMicroseconds = 0xA1B2C3D4
+	magicNanoseconds  = 0xA1B23C4D
+
+	blockSectionHeader    = 0x0A0D0D0A
+	blockInterface        = 0x00000001
+	blockPacket           = 0x00000002
+	blockSimplePacket     = 0x00000003
+	blockEnhancedPacket   = 0x00000006
+	byteOrderMagic        = 0x1A2B3C4D
+	optionEnd             = 0
+	optionTimestampResol  = 9
+	optionTimestampOffset = 14
+
+	// maxBlockSize bounds the memory used by a corrupt capture
+	maxBlockSize = 16 << 20
+)
+
+// Reader reads the SAP datagrams of a pcap or pcapng capture, skipping every other packet.
+type Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	ng    bool
+
+	// pcap
+	linkType   uint16
+	resolution time.Duration
+
+	// pcapng, the interfaces of the current section
+	interfaces []ngInterface
+}
+
+type ngInterface struct {
+	linkType uint16
+
+	// The number of timestamp units per second and the offset of the timestamps, in seconds
+	unitsPerSecond uint64
+	offset         int64
+}
+
+// NewReader returns a Reader of the capture read from r, telling pcap and pcapng captures apart.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	magic, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, errUnknownFormat
+	}
+
+	if binary.BigEndian.Uint32(magic) == blockSectionHeader {
+		reader.ng = true
+		return reader, nil
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader.r, header); err != nil {
+		return nil, errUnknownFormat
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header[0:4]) {
+		case magicMicroseconds:
+			reader.resolution = time.Microsecond
+		case magicNanoseconds:
+			reader.resolution = time.Nanosecond
+		default:
+			continue
+		}
+		reader.order = order
+	}
+
+	if reader.order == nil {
+		return nil, errUnknownFormat
+	}
+
+	if reader.order.Uint16(header[4:6]) != 2 {
+		return nil, errUnsupportedVersion
+	}
+
+	// The upper bits of the link type field hold the FCS length
+	reader.linkType = uint16(reader.order.Uint32(header[20:24]))
+
+	return reader, nil
+}
+
+// Next returns the next SAP datagram of the capture, or io.EOF at its end.
+// The data of the record is not reused by later calls.
+func (r *Reader) Next() (Record, error) {
+	for {
+		var linkType uint16
+		var timestamp time.Time
+		var frame []byte
+		var err error
+
+		if r.ng {
+			linkType, timestamp, frame, err = r.nextBlock()
+		} else {
+			linkType, timestamp, frame, err = r.nextRecord()
+		}
+		if err != nil {
+			return Record{}, err
+		}
+		if frame == nil {
+			continue
+		}
+
+		if record, ok := decodeFrame(linkType, frame); ok {
+			record.Timestamp = timestamp
+			return record, nil
+		}
+	}
+}
+
+// nextRecord reads the next packet of a pcap capture.
+func (r *Reader) nextRecord() (uint16, time.Time, []byte, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, time.Time{}, nil, errTruncatedCapture
+		}
+		return 0, time.Time{}, nil, err
+	}
+
+	seconds := int64(r.order.Uint32(header[0:4]))
+	fraction := time.Duration(r.order.Uint32(header[4:8])) * r.resolution
+	capturedSize := r.order.Uint32(header[8:12])
+	if capturedSize > maxBlockSize {
+		return 0, time.Time{}, nil, errTruncatedCapture
+	}
+
+	frame := make([]byte, capturedSize)
+	if _, err := io.ReadFull(r.r, frame); err != nil {
+		return 0, time.Time{}, nil, errTruncatedCapture
+	}
+
+	return r.linkType, time.Unix(seconds, int64(fraction)), frame, nil
+}
+
+// nextBlock reads the next block of a pcapng capture. The frame is nil if the block is not a packet.
+func (r *Reader) nextBlock() (uint16, time.Time, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, time.Time{}, nil, errTruncatedCapture
+		}
+		return 0, time.Time{}, nil, err
+	}
+
+	blockType := binary.BigEndian.Uint32(header[0:4])
+	if blockType == blockSectionHeader {
+		// The byte order of the section is given by its header
+		magic := make([]byte, 4)
+		if _, err := io.ReadFull(r.r, magic); err != nil {
+			return 0, time.Time{}, nil, errTruncatedCapture
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == byteOrderMagic:
+			r.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == byteOrderMagic:
+			r.order = binary.BigEndian
+		default:
+			return 0, time.Time{}, nil, errInvalidBlock
+		}
+		r.interfaces = nil
+
+		body, err := r.readBody(header[4:8], 4)
+		if err != nil {
+			return 0, time.Time{}, nil, err
+		}
+		if r.order.Uint16(body[0:2]) != 1 {
+			return 0, time.Time{}, nil, errUnsupportedVersion
+		}
+
+		return 0, time.Time{}, nil, nil
+	}
+
+	if r.order == nil {
+		return 0, time.Time{}, nil, errInvalidBlock
+	}
+	blockType = r.order.Uint32(header[0:4])
+
+	body, err := r.readBody(header[4:8], 0)
+	if err != nil {
+		return 0, time.Time{}, nil, err
+	}
+
+	switch blockType {
+	case blockInterface:
+		if len(body) < 8 {
+			return 0, time.Time{}, nil, errInvalidBlock
+		}
+		r.interfaces = append(r.interfaces, r.parseInterface(body))
+		return 0, time.Time{}, nil, nil
+	case blockEnhancedPacket:
+		if len(body) < 20 {
+			return 0, time.Time{}, nil, errInvalidBlock
+		}
+		return r.packet(r.order.Uint32(body[0:4]), body[4:12], r.order.Uint32(body[12:16]), body[20:])
+	case blockPacket:
+		if len(body) < 20 {
+			return 0, time.Time{}, nil, errInvalidBlock
+		}
+		return r.packet(uint32(r.order.Uint16(body[0:2])), body[4:12], r.order.Uint32(body[12:16]), body[20:])
+	case blockSimplePacket:
+		if len(body) < 4 || len(r.interfaces) == 0 {
+			return 0, time.Time{}, nil, errInvalidBlock
+		}
+		// Simple packets carry no timestamp and no captured length, the frame is padded to 32 bits
+		frame := body[4:]
+		if size := r.order.Uint32(body[0:4]); int(size) < len(frame) {
+			frame = frame[:size]
+		}
+		return r.interfaces[0].linkType, time.Time{}, frame, nil
+	default:
+		return 0, time.Time{}, nil, nil
+	}
+}
+
+// readBody reads the rest of a block whose total length is lengthField, once read bytes of it were read
+// past the block type and length. The trailing copy of the length is dropped.
+func (r *Reader) readBody(lengthField []byte, read int) ([]byte, error) {
+	length := r.order.Uint32(lengthField)
+	if length < uint32(12+read) || length%4 != 0 || length > maxBlockSize {
+		return nil, errInvalidBlock
+	}
+
+	body := make([]byte, length-8-uint32(read))
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		return nil, errTruncatedCapture
+	}
+
+	return body[:len(body)-4], nil
+}
+
+// parseInterface reads the link type and timestamp options of an interface description block.
+func (r *Reader) parseInterface(body []byte) ngInterface {
+	ifi := ngInterface{linkType: r.order.Uint16(body[0:2]), unitsPerSecond: 1e6}
+
+	options := body[8:]
+	for len(options) >= 4 {
+		code := r.order.Uint16(options[0:2])
+		length := int(r.order.Uint16(options[2:4]))
+		padded := (length + 3) &^ 3
+		if code == optionEnd || len(options) < 4+padded {
+			break
+		}
+		value := options[4 : 4+length]
+
+		switch {
+		case code == optionTimestampResol && length == 1:
+			exponent := uint64(value[0] & 0x7F)
+			if value[0]&0x80 != 0 {
+				if exponent < 64 {
+					ifi.unitsPerSecond = 1 << exponent
+				}
+			} else if exponent <= 19 {
+				ifi.unitsPerSecond = uint64(math.Pow10(int(exponent)))
+			}
+		case code == optionTimestampOffset && length == 8:
+			ifi.offset = int64(r.order.Uint64(value))
+		}
+
+		options = options[4+padded:]
+	}
+
+	return ifi
+}
+
+// packet returns the link type, timestamp and frame of a packet block of the interface.
+func (r *Reader) packet(interfaceID uint32, timestamp []byte, capturedSize uint32, data []byte) (uint16, time.Time, []byte, error) {
+	if int(interfaceID) >= len(r.interfaces) {
+		return 0, time.Time{}, nil, errUnknownInterface
+	}
+	if int(capturedSize) > len(data) {
+		return 0, time.Time{}, nil, errInvalidBlock
+	}
+
+	ifi := r.interfaces[interfaceID]
+	units := uint64(r.order.Uint32(timestamp[0:4]))<<32 | uint64(r.order.Uint32(timestamp[4:8]))
+	seconds := units / ifi.unitsPerSecond
+	hi, lo := bits.Mul64(units%ifi.unitsPerSecond, 1e9)
+	nanoseconds, _ := bits.Div64(hi, lo, ifi.unitsPerSecond)
+
+	return ifi.linkType, time.Unix(int64(seconds)+ifi.offset, int64(nanoseconds)), data[:capturedSize], nil
+}
@@ -0,0 +1,227 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net/netip"
+)
+
+// Format is the file format of a capture written by a Writer
+type Format uint8
+
+const (
+	// The libpcap format, with nanosecond timestamps
+	FormatPcap Format = iota
+
+	// The pcapng format, with a single Ethernet interface and nanosecond timestamps
+	FormatPcapNG
+)
+
+const (
+	snapLength = 65535
+	hopLimit   = 255
+)
+
+var (
+	sourceMAC  = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	unicastMAC = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+// Writer writes SAP datagrams to a capture, as UDP packets in Ethernet frames.
+type Writer struct {
+	w      io.Writer
+	format Format
+}
+
+// NewWriter writes the header of a capture of the given format to w and returns a Writer of its packets.
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	var header []byte
+	order := binary.LittleEndian
+
+	if format == FormatPcapNG {
+		// Section header block, of unspecified section length
+		header = order.AppendUint32(header, blockSectionHeader)
+		header = order.AppendUint32(header, 28)
+		header = order.AppendUint32(header, byteOrderMagic)
+		header = order.AppendUint16(header, 1)
+		header = order.AppendUint16(header, 0)
+		header = order.AppendUint64(header, math.MaxUint64)
+		header = order.AppendUint32(header, 28)
+
+		// Interface description block, with nanosecond timestamps
+		header = order.AppendUint32(header, blockInterface)
+		header = order.AppendUint32(header, 32)
+		header = order.AppendUint16(header, LinkTypeEthernet)
+		header = order.AppendUint16(header, 0)
+		header = order.AppendUint32(header, snapLength)
+		header = order.AppendUint16(header, optionTimestampResol)
+		header = order.AppendUint16(header, 1)
+		header = append(header, 9, 0, 0, 0)
+		header = order.AppendUint32(header, optionEnd)
+		header = order.AppendUint32(header, 32)
+	} else {
+		header = order.AppendUint32(header, magicNanoseconds)
+		header = order.AppendUint16(header, 2)
+		header = order.AppendUint16(header, 4)
+		header = order.AppendUint32(header, 0)
+		header = order.AppendUint32(header, 0)
+		header = order.AppendUint32(header, snapLength)
+		header = order.AppendUint32(header, uint32(LinkTypeEthernet))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w, format: format}, nil
+}
+
+// Write writes the datagram of the record, sent from its source to its destination at its timestamp.
+// The timestamp must be set, between 1970 and 2106 as pcap holds unsigned 32 bit seconds.
+func (w *Writer) Write(r Record) error {
+	if r.Timestamp.IsZero() {
+		return errZeroTimestamp
+	}
+
+	// The range of pcap, pcapng nanoseconds since 1970 overflow in 2262
+	if seconds := r.Timestamp.Unix(); seconds < 0 || seconds > math.MaxUint32 {
+		return errTimestampRange
+	}
+
+	// The frame fits in a UDP packet, so its length fits in the 32 bit lengths
+	frame, err := encodeFrame(r)
+	if err != nil {
+		return err
+	}
+
+	order := binary.LittleEndian
+	nanoseconds := uint64(r.Timestamp.UnixNano())
+
+	var header []byte
+	if w.format == FormatPcapNG {
+		padding := (4 - len(frame)%4) % 4
+		length := uint32(32 + len(frame) + padding)
+
+		header = order.AppendUint32(header, blockEnhancedPacket)
+		header = order.AppendUint32(header, length)
+		header = order.AppendUint32(header, 0)
+		header = order.AppendUint32(header, uint32(nanoseconds>>32))
+		header = order.AppendUint32(header, uint32(nanoseconds))
+		header = order.AppendUint32(header, uint32(len(frame)))
+		header = order.AppendUint32(header, uint32(len(frame)))
+
+		frame = append(frame, make([]byte, padding)...)
+		frame = order.AppendUint32(frame, length)
+	} else {
+		header = order.AppendUint32(header, uint32(r.Timestamp.Unix()))
+		header = order.AppendUint32(header, uint32(r.Timestamp.Nanosecond()))
+		header = order.AppendUint32(header, uint32(len(frame)))
+		header = order.AppendUint32(header, uint32(len(frame)))
+	}
+
+	if _, err := w.w.Write(append(header, frame...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// encodeFrame returns the Ethernet frame of the UDP datagram of the record.
+func encodeFrame(r Record) ([]byte, error) {
+	src, dst := r.Source.Addr().Unmap(), r.Destination.Addr().Unmap()
+	if !src.IsValid() || src.Is4() != dst.Is4() {
+		return nil, errAddressFamily
+	}
+
+	udpSize := udpHeaderSize + len(r.Data)
+	ipHeaderSize := ipv6HeaderSize
+	if src.Is4() {
+		ipHeaderSize = ipv4HeaderSize
+	}
+	if ipHeaderSize+udpSize > math.MaxUint16 {
+		return nil, errDatagramTooLarge
+	}
+
+	frame := make([]byte, 0, 14+ipHeaderSize+udpSize)
+	frame = append(frame, destinationMAC(dst)...)
+	frame = append(frame, sourceMAC...)
+
+	if src.Is4() {
+		frame = binary.BigEndian.AppendUint16(frame, etherTypeIPv4)
+
+		ip := make([]byte, ipv4HeaderSize)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(ipv4HeaderSize+udpSize))
+		ip[8] = hopLimit
+		ip[9] = protocolUDP
+		copy(ip[12:16], src.AsSlice())
+		copy(ip[16:20], dst.AsSlice())
+		binary.BigEndian.PutUint16(ip[10:12], ^checksum(0, ip))
+
+		frame = append(frame, ip...)
+	} else {
+		frame = binary.BigEndian.AppendUint16(frame, etherTypeIPv6)
+
+		ip := make([]byte, ipv6HeaderSize)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(udpSize))
+		ip[6] = protocolUDP
+		ip[7] = hopLimit
+		copy(ip[8:24], src.AsSlice())
+		copy(ip[24:40], dst.AsSlice())
+
+		frame = append(frame, ip...)
+	}
+
+	udp := make([]byte, udpHeaderSize, udpSize)
+	binary.BigEndian.PutUint16(udp[0:2], r.Source.Port())
+	binary.BigEndian.PutUint16(udp[2:4], r.Destination.Port())
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpSize))
+	udp = append(udp, r.Data...)
+
+	// The checksum covers a pseudo header of the addresses, protocol and length
+	sum := checksum(0, src.AsSlice())
+	sum = checksum(sum, dst.AsSlice())
+	sum = checksum(sum, []byte{0, protocolUDP, byte(udpSize >> 8), byte(udpSize)})
+	udpChecksum := ^checksum(sum, udp)
+	if udpChecksum == 0 {
+		udpChecksum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(udp[6:8], udpChecksum)
+
+	return append(frame, udp...), nil
+}
+
+// destinationMAC returns the Ethernet address frames to dst are sent to.
+func destinationMAC(dst netip.Addr) []byte {
+	if !dst.IsMulticast() {
+		return unicastMAC
+	}
+
+	b := dst.AsSlice()
+	if dst.Is4() {
+		// RFC 1112 section 6.4
+		return []byte{0x01, 0x00, 0x5E, b[1] & 0x7F, b[2], b[3]}
+	}
+
+	// RFC 2464 section 7
+	return []byte{0x33, 0x33, b[12], b[13], b[14], b[15]}
+}
+
+// checksum adds data to the ones' complement sum of 16 bit words sum, as used by IP and UDP.
+func checksum(sum uint16, data []byte) uint16 {
+	total := uint32(sum)
+	for i := 0; i+1 < len(data); i += 2 {
+		total += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		total += uint32(data[len(data)-1]) << 8
+	}
+
+	for total > 0xFFFF {
+		total = total>>16 + total&0xFFFF
+	}
+
+	return uint16(total)
+}
@@ -0,0 +1,67 @@
+// Package pgp signs and verifies SAP packets using OpenPGP detached signatures
+package pgp
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/openaudiocollective/sap"
+)
+
+var errNoPrivateKey = errors.New("pgp: entity has no usable private signing key")
+
+// Signer produces binary OpenPGP detached signatures.
+type Signer struct {
+	entity *openpgp.Entity
+	config *packet.Config
+}
+
+// NewSigner returns a Signer signing with the private key of entity, which must be decrypted.
+// config may be nil to use the defaults.
+func NewSigner(entity *openpgp.Entity, config *packet.Config) (*Signer, error) {
+	if entity.PrivateKey == nil || entity.PrivateKey.Encrypted {
+		return nil, errNoPrivateKey
+	}
+
+	return &Signer{entity: entity, config: config}, nil
+}
+
+// AuthType returns sap.PGP
+func (s *Signer) AuthType() sap.AuthType {
+	return sap.PGP
+}
+
+// Sign returns the binary OpenPGP detached signature of data.
+func (s *Signer) Sign(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	if err := openpgp.DetachSign(&b, s.entity, bytes.NewReader(data), s.config); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Verifier checks OpenPGP detached signatures against a keyring of trusted public keys.
+type Verifier struct {
+	keyring openpgp.KeyRing
+	config  *packet.Config
+}
+
+// NewVerifier returns a Verifier accepting signatures made by the keys in keyring.
+// Use openpgp.ReadKeyRing or openpgp.ReadArmoredKeyRing to load it. config may be nil to use the defaults.
+func NewVerifier(keyring openpgp.KeyRing, config *packet.Config) *Verifier {
+	return &Verifier{keyring: keyring, config: config}
+}
+
+// AuthType returns sap.PGP
+func (v *Verifier) AuthType() sap.AuthType {
+	return sap.PGP
+}
+
+// Verify checks that signature is a detached signature of data by a key of the keyring.
+func (v *Verifier) Verify(data, signature []byte) error {
+	_, err := openpgp.CheckDetachedSignature(v.keyring, bytes.NewReader(data), bytes.NewReader(signature), v.config)
+	return err
+}
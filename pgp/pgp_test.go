@@ -0,0 +1,87 @@
+package pgp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/openaudiocollective/sap"
+)
+
+func createTestEntity(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "sap test", name+"@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("NewEntity failed with error: %v", err)
+	}
+
+	return entity
+}
+
+func TestSignAndVerify(t *testing.T) {
+	announcer := createTestEntity(t, "announcer")
+	forger := createTestEntity(t, "forger")
+
+	signer, err := NewSigner(announcer, nil)
+	if err != nil {
+		t.Fatalf("NewSigner failed with error: %v", err)
+	}
+
+	forgerSigner, err := NewSigner(forger, nil)
+	if err != nil {
+		t.Fatalf("NewSigner failed with error: %v", err)
+	}
+
+	verifier := NewVerifier(openpgp.EntityList{announcer}, nil)
+
+	testCases := []struct {
+		name      string
+		signer    sap.Signer
+		wantValid bool
+	}{
+		{
+			name:      "Trusted Key",
+			signer:    signer,
+			wantValid: true,
+		},
+		{
+			name:      "Untrusted Key",
+			signer:    forgerSigner,
+			wantValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := sap.NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, sap.WithMessageType(sap.Deletion), sap.WithSigner(tc.signer))
+			if err != nil {
+				t.Fatalf("NewPacket failed with error: %v", err)
+			}
+
+			data, err := p.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			p2 := &sap.Packet{}
+			err = p2.Unmarshal(data, sap.WithVerifiers(verifier))
+			if tc.wantValid && err != nil {
+				t.Errorf("Unmarshal failed with error: %v", err)
+			}
+			if !tc.wantValid && err == nil {
+				t.Error("Expected the signature to be rejected")
+			}
+		})
+	}
+}
+
+func TestNewSignerWithoutPrivateKey(t *testing.T) {
+	entity := createTestEntity(t, "public")
+	entity.PrivateKey = nil
+
+	if _, err := NewSigner(entity, nil); err != errNoPrivateKey {
+		t.Errorf("Expected error %v, but got %v", errNoPrivateKey, err)
+	}
+}
@@ -0,0 +1,77 @@
+package sap
+
+// Policy sets how strictly packets are checked when they are marshaled or unmarshaled
+type Policy uint8
+
+const (
+	// Lenient accepts any packet that can be decoded and follows the RFC 2974 rules for listeners:
+	// the reserved bit is ignored and packets with a zero message id hash are kept.
+	// When marshaling, the flags are truncated to their bit and the authentication length is that
+	// of the authentication data, but the header is otherwise written as is.
+	Lenient Policy = iota
+
+	// Strict rejects packets that do not comply with RFC 2974, see Header.Validate.
+	// When unmarshaling, the reserved bit is still ignored as listeners MUST do.
+	Strict
+)
+
+// Marshal the packet with the given policy. With Strict, Marshal fails if the header is not valid.
+func WithEncodePolicy(policy Policy) PacketOption {
+	return packetOption(func(p *Packet) {
+		p.EncodePolicy = policy
+	})
+}
+
+// Unmarshal the packet or header with the given policy. With Strict, packets with a version other than 1
+// or a zero message id hash are rejected.
+func WithDecodePolicy(policy Policy) UnmarshalOption {
+	return func(c *unmarshalConfig) {
+		c.policy = policy
+	}
+}
+
+// Validate checks that the header complies with RFC 2974 and can be sent as is:
+// the version is 1, the reserved bit is 0, the one bit fields hold 0 or 1,
+// the authentication length matches the authentication data,
+// the message id hash is not zero and the address type matches the originating source.
+func (h Header) Validate() error {
+	_, _, err := h.validate(true)
+
+	return err
+}
+
+// validate checks the header and returns the name and position of the offending field along with the error.
+// The reserved bit and the consistency of the header with its fields are only checked when sending,
+// an unmarshaled header is always consistent.
+func (h Header) validate(sending bool) (field string, offset int, err error) {
+	if h.Version != 1 {
+		return "Flags", 0, ErrInvalidVersion
+	}
+
+	if sending {
+		if h.Reserved != 0 {
+			return "Flags", 0, ErrReservedBitSet
+		}
+
+		if h.AddressType > 1 || h.MessageType > 1 || h.Encrypted > 1 || h.Compressed > 1 {
+			return "Flags", 0, ErrInvalidFlag
+		}
+
+		if int(h.AuthenticationLength) != len(h.AuthenticationData) {
+			return "Authentication Length", 1, ErrAuthLengthMismatch
+		}
+	}
+
+	if h.MessageIDHash == 0 {
+		return "Message Identifier Hash", 2, ErrZeroMessageIDHash
+	}
+
+	if sending {
+		isIPv4 := h.OriginatingSource.To4() != nil
+		if h.OriginatingSource.To16() == nil || isIPv4 != (h.AddressType == IPv4) {
+			return "Originating Source", 4, ErrAddressTypeMismatch
+		}
+	}
+
+	return "", 0, nil
+}
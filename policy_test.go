@@ -0,0 +1,205 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderValidate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mockHeader    Header
+		expectedError error
+	}{
+		{
+			name:       "Valid",
+			mockHeader: CreateMockHeader(Header{MessageIDHash: 1}),
+		},
+		{
+			name: "InvalidVersion",
+			mockHeader: func() Header {
+				h := CreateMockHeader(Header{MessageIDHash: 1})
+				h.Version = 3
+				return h
+			}(),
+			expectedError: ErrInvalidVersion,
+		},
+		{
+			name:          "ReservedBitSet",
+			mockHeader:    CreateMockHeader(Header{MessageIDHash: 1, Reserved: 1}),
+			expectedError: ErrReservedBitSet,
+		},
+		{
+			name:          "InvalidFlag",
+			mockHeader:    CreateMockHeader(Header{MessageIDHash: 1, Compressed: 2}),
+			expectedError: ErrInvalidFlag,
+		},
+		{
+			name: "AuthLengthMismatch",
+			mockHeader: func() Header {
+				h := CreateMockHeader(Header{MessageIDHash: 1, AuthenticationLength: 2})
+				h.AuthenticationData = h.AuthenticationData[:1]
+				return h
+			}(),
+			expectedError: ErrAuthLengthMismatch,
+		},
+		{
+			name: "ZeroMessageIDHash",
+			mockHeader: func() Header {
+				h := CreateMockHeader(Header{})
+				h.MessageIDHash = 0
+				return h
+			}(),
+			expectedError: ErrZeroMessageIDHash,
+		},
+		{
+			name:          "IPv6AddressTypeWithIPv4Source",
+			mockHeader:    CreateMockHeader(Header{MessageIDHash: 1, AddressType: IPv6, OriginatingSource: net.ParseIP("192.0.2.1")}),
+			expectedError: ErrAddressTypeMismatch,
+		},
+		{
+			name:          "IPv4AddressTypeWithIPv6Source",
+			mockHeader:    CreateMockHeader(Header{MessageIDHash: 1, OriginatingSource: net.ParseIP("2001:db8::68")}),
+			expectedError: ErrAddressTypeMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.mockHeader.Validate()
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+// TestHeaderMarshalToMasksFlags checks that out of range flags do not spill over the neighbouring bits.
+func TestHeaderMarshalToMasksFlags(t *testing.T) {
+	h := CreateMockHeader(Header{})
+	h.Version = 3
+	h.Compressed = 2
+
+	data, err := h.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	if data[0] != 0x20 {
+		t.Errorf("expected flags %#x, got %#x", 0x20, data[0])
+	}
+}
+
+func TestEncodePolicy(t *testing.T) {
+	p, err := NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, WithEncodePolicy(Strict))
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	if _, err := p.Marshal(); err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	p.Reserved = 1
+	if _, err := p.Marshal(); !errors.Is(err, ErrReservedBitSet) {
+		t.Errorf("Expected error %v, but got %v", ErrReservedBitSet, err)
+	}
+
+	p.EncodePolicy = Lenient
+	if _, err := p.Marshal(); err != nil {
+		t.Errorf("Marshal failed with error: %v", err)
+	}
+}
+
+func TestDecodePolicy(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         []byte
+		policy        Policy
+		expectedError error
+	}{
+		{
+			name:   "LenientZeroMessageIDHash",
+			input:  []byte{0x20, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x02, 0x01},
+			policy: Lenient,
+		},
+		{
+			name:          "StrictZeroMessageIDHash",
+			input:         []byte{0x20, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x02, 0x01},
+			policy:        Strict,
+			expectedError: ErrZeroMessageIDHash,
+		},
+		{
+			name:   "StrictReservedBitIgnored",
+			input:  []byte{0x28, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01},
+			policy: Strict,
+		},
+		{
+			name:          "StrictInvalidVersion",
+			input:         []byte{0x60, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01},
+			policy:        Strict,
+			expectedError: ErrInvalidVersion,
+		},
+		{
+			name:          "StrictVersionZero",
+			input:         []byte{0x00, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01},
+			policy:        Strict,
+			expectedError: ErrInvalidVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Packet{}
+			err := p.Unmarshal(tc.input, WithDecodePolicy(tc.policy))
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestHeaderDecodePolicy(t *testing.T) {
+	input := []byte{0x20, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x02, 0x01}
+
+	h := Header{}
+	if err := h.Unmarshal(input, WithDecodePolicy(Strict)); !errors.Is(err, ErrZeroMessageIDHash) {
+		t.Errorf("Expected error %v, but got %v", ErrZeroMessageIDHash, err)
+	}
+
+	// The policy is not kept from one call to the next
+	if err := h.Unmarshal(input, WithDecodePolicy(Lenient)); err != nil {
+		t.Errorf("Unmarshal failed with error: %v", err)
+	}
+
+	p := &Packet{}
+	if err := p.Unmarshal(input, WithDecodePolicy(Strict)); !errors.Is(err, ErrZeroMessageIDHash) {
+		t.Errorf("Expected error %v, but got %v", ErrZeroMessageIDHash, err)
+	}
+
+	if err := p.Unmarshal(input); err != nil {
+		t.Errorf("Unmarshal failed with error: %v", err)
+	}
+}
+
+// TestHeaderMarshalToAuthLength checks that a lenient header is written with the length of its authentication data.
+func TestHeaderMarshalToAuthLength(t *testing.T) {
+	h := CreateMockHeader(Header{MessageIDHash: 1, AuthenticationLength: 2})
+	h.AuthenticationData = h.AuthenticationData[:1]
+
+	data, err := h.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	h2 := Header{}
+	if err := h2.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	if h2.AuthenticationLength != 1 || !reflect.DeepEqual(h2.AuthenticationData, h.AuthenticationData) {
+		t.Errorf("expected authentication data %v, got %v", h.AuthenticationData, h2.AuthenticationData)
+	}
+}
@@ -0,0 +1,157 @@
+// Package replay resends the SAP packets of a capture with their original timing
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/openaudiocollective/sap/pcap"
+)
+
+var (
+	errNoDestination = errors.New("replay: record has no destination")
+	errAddressFamily = errors.New("replay: source is not of the address family of the packet")
+)
+
+// Reader returns the records to replay, such as a *pcap.Reader. Next returns io.EOF after the last one.
+type Reader interface {
+	Next() (pcap.Record, error)
+}
+
+// Option configures Replay
+type Option func(*config)
+
+type config struct {
+	speed       float64
+	source      netip.Addr
+	destination net.Addr
+}
+
+// Replay speed times faster than recorded, 2 halving the time between packets.
+// A speed of zero or less sends the packets without waiting.
+func WithSpeed(speed float64) Option {
+	return func(c *config) {
+		c.speed = speed
+	}
+}
+
+// Rewrite the originating source of the packets. The packets are otherwise sent as recorded,
+// so their signature, if any, no longer verifies. Replay fails on a packet whose originating source
+// is not of the address family of addr, as SAPv0 packets and the rest of their header depend on it.
+func WithSource(addr netip.Addr) Option {
+	return func(c *config) {
+		c.source = addr.Unmap()
+	}
+}
+
+// Send the packets to addr instead of their recorded destination
+func WithDestination(addr net.Addr) Option {
+	return func(c *config) {
+		c.destination = addr
+	}
+}
+
+// Replay sends the packets read from r over conn, spaced as they were captured, until r is exhausted or ctx is done.
+// It returns the number of packets sent.
+func Replay(ctx context.Context, r Reader, conn net.PacketConn, opts ...Option) (int, error) {
+	c := config{speed: 1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var start time.Time
+	var first time.Time
+	sent := 0
+
+	for {
+		record, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		if sent == 0 {
+			start, first = time.Now(), record.Timestamp
+		} else if c.speed > 0 {
+			offset := time.Duration(float64(record.Timestamp.Sub(first)) / c.speed)
+			if err := sleepUntil(ctx, start.Add(offset)); err != nil {
+				return sent, err
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		dst := c.destination
+		if dst == nil {
+			if !record.Destination.IsValid() {
+				return sent, errNoDestination
+			}
+			dst = net.UDPAddrFromAddrPort(record.Destination)
+		}
+
+		data := record.Data
+		if c.source.IsValid() {
+			if data, err = rewriteSource(data, c.source); err != nil {
+				return sent, err
+			}
+		}
+
+		if _, err := conn.WriteTo(data, dst); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+}
+
+// sleepUntil waits until t or until ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rewriteSource returns a copy of the SAP packet data with its originating source replaced by addr,
+// which must be of the same address family. Data too short to hold an originating source is returned as is.
+func rewriteSource(data []byte, addr netip.Addr) ([]byte, error) {
+	const (
+		sourceOffset = 4
+		addressType  = 0x10
+	)
+
+	if len(data) < sourceOffset+4 {
+		return data, nil
+	}
+
+	// The address type also tells SAPv0 packets, which only have IPv4 sources, apart
+	if (data[0]&addressType != 0) != addr.Is6() {
+		return nil, errAddressFamily
+	}
+
+	if len(data) < sourceOffset+addr.BitLen()/8 {
+		return data, nil
+	}
+
+	out := append([]byte(nil), data...)
+	copy(out[sourceOffset:], addr.AsSlice())
+
+	return out, nil
+}
@@ -0,0 +1,185 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/openaudiocollective/sap"
+	"github.com/openaudiocollective/sap/pcap"
+)
+
+type sliceReader []pcap.Record
+
+func (r *sliceReader) Next() (pcap.Record, error) {
+	if len(*r) == 0 {
+		return pcap.Record{}, io.EOF
+	}
+
+	record := (*r)[0]
+	*r = (*r)[1:]
+
+	return record, nil
+}
+
+func createTestPacket(t *testing.T, source, payload string) []byte {
+	t.Helper()
+
+	p, err := sap.NewPacket([]byte(payload), net.UDPAddr{IP: net.ParseIP(source)})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	return data
+}
+
+func TestReplay(t *testing.T) {
+	target, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer target.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	r := &sliceReader{
+		{Timestamp: start, Data: createTestPacket(t, "192.0.2.1", "v=0\r\ns=First\r\n")},
+		{Timestamp: start.Add(10 * time.Second), Data: createTestPacket(t, "192.0.2.1", "v=0\r\ns=Second\r\n")},
+	}
+
+	// Ten seconds apart at a hundred times the speed
+	source := netip.MustParseAddr("198.51.100.7")
+	sent, err := Replay(context.Background(), r, conn, WithSpeed(100), WithSource(source), WithDestination(target.LocalAddr()))
+	if err != nil {
+		t.Fatalf("Replay failed with error: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("expected %d packets sent, got %d", 2, sent)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the replay to take at least 100ms, took %v", elapsed)
+	}
+
+	buf := make([]byte, 1500)
+	for _, want := range []string{"v=0\r\ns=First\r\n", "v=0\r\ns=Second\r\n"} {
+		if err := target.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatalf("SetReadDeadline failed with error: %v", err)
+		}
+
+		n, _, err := target.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("ReadFrom failed with error: %v", err)
+		}
+
+		p := sap.Packet{}
+		if err := p.Unmarshal(buf[:n]); err != nil {
+			t.Fatalf("Unmarshal failed with error: %v", err)
+		}
+
+		if p.AddressType != sap.IPv4 || p.Source() != source || string(p.Payload) != want {
+			t.Errorf("unexpected packet %v", p)
+		}
+	}
+}
+
+func TestReplayCanceled(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	r := &sliceReader{
+		{Timestamp: now, Data: createTestPacket(t, "192.0.2.1", "v=0\r\n")},
+		{Timestamp: now.Add(time.Hour), Data: createTestPacket(t, "192.0.2.1", "v=0\r\n")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	sent, err := Replay(ctx, r, conn, WithDestination(conn.LocalAddr()))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error %v, but got %v", context.DeadlineExceeded, err)
+	}
+	if sent != 1 {
+		t.Errorf("expected %d packet sent, got %d", 1, sent)
+	}
+}
+
+func TestRewriteSource(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          []byte
+		addr          netip.Addr
+		expectedError error
+	}{
+		{
+			name: "IPv4",
+			data: createTestPacket(t, "192.0.2.1", "v=0\r\n"),
+			addr: netip.MustParseAddr("198.51.100.7"),
+		},
+		{
+			name: "IPv6",
+			data: createTestPacket(t, "2001:db8::68", "v=0\r\n"),
+			addr: netip.MustParseAddr("2001:db8::1"),
+		},
+		{
+			name:          "IPv6SourceOnIPv4Packet",
+			data:          createTestPacket(t, "192.0.2.1", "v=0\r\n"),
+			addr:          netip.MustParseAddr("2001:db8::1"),
+			expectedError: errAddressFamily,
+		},
+		{
+			name:          "IPv4SourceOnIPv6Packet",
+			data:          createTestPacket(t, "2001:db8::68", "v=0\r\n"),
+			addr:          netip.MustParseAddr("198.51.100.7"),
+			expectedError: errAddressFamily,
+		},
+		{
+			name:          "IPv6SourceOnSAPv0Packet",
+			data:          []byte{0x00, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01, 'v', '=', '0', '\r', '\n'},
+			addr:          netip.MustParseAddr("2001:db8::1"),
+			expectedError: errAddressFamily,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := rewriteSource(tc.data, tc.addr)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+
+			p := sap.Packet{}
+			if err := p.Unmarshal(data); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			if p.Source() != tc.addr || string(p.Payload) != "v=0\r\n" {
+				t.Errorf("unexpected packet %v", p)
+			}
+		})
+	}
+
+	if got, err := rewriteSource([]byte{0x20, 0x00}, netip.MustParseAddr("2001:db8::1")); err != nil || len(got) != 2 {
+		t.Errorf("expected a short packet to be left as is, got % x and error %v", got, err)
+	}
+}
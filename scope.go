@@ -0,0 +1,137 @@
+package sap
+
+import (
+	"net"
+	"net/netip"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Port is the UDP port SAP announcements are sent to (RFC 2974 section 3)
+const Port = 9875
+
+var (
+	// GlobalIPv4Group is the SAP group of IPv4 sessions with global scope
+	GlobalIPv4Group = net.IPv4(224, 2, 127, 254)
+
+	// IPv4LocalScope is the IPv4 Local Scope of RFC 2365 section 6.1
+	IPv4LocalScope = &net.IPNet{IP: net.IPv4(239, 255, 0, 0).To4(), Mask: net.CIDRMask(16, 32)}
+
+	// IPv4OrganizationLocalScope is the IPv4 Organization Local Scope of RFC 2365 section 6.2
+	IPv4OrganizationLocalScope = &net.IPNet{IP: net.IPv4(239, 192, 0, 0).To4(), Mask: net.CIDRMask(14, 32)}
+
+	// administrativeScopes is the range of administratively scoped IPv4 multicast addresses (RFC 2365 section 6)
+	administrativeScopes = &net.IPNet{IP: net.IPv4(239, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}
+)
+
+// GroupFor returns the SAP group, with the SAP port, that announcements of a session sent to
+// the multicast address session belong to (RFC 2974 section 3).
+//
+// Global IPv4 sessions are announced on 224.2.127.254. Administratively scoped IPv4 sessions are announced
+// on the highest address of their scope, scopes is the list of scopes configured on the network and defaults to the
+// IPv4 Local Scope and IPv4 Organization Local Scope. Administratively scoped addresses outside of all the scopes,
+// such as the 239.69.0.0/16 range commonly used by AES67 devices, are taken to be in the IPv4 Local Scope,
+// where these devices announce their sessions.
+// IPv6 sessions are announced on FF0X::2:7FFE, where X is the scope of the session address.
+func GroupFor(session net.IP, scopes ...*net.IPNet) (*net.UDPAddr, error) {
+	if !session.IsMulticast() {
+		return nil, errNotMulticast
+	}
+
+	if ip4 := session.To4(); ip4 != nil {
+		return &net.UDPAddr{IP: ipv4Group(ip4, scopes), Port: Port}, nil
+	}
+
+	scope := session[1] & 0x0F
+	if scope == 0x0 || scope == 0xF {
+		return nil, errReservedScope
+	}
+
+	group := net.ParseIP("ff00::2:7ffe")
+	group[1] = scope
+
+	return &net.UDPAddr{IP: group, Port: Port}, nil
+}
+
+// GroupAddrPort is GroupFor with netip types.
+func GroupAddrPort(session netip.Addr, scopes ...netip.Prefix) (netip.AddrPort, error) {
+	nets := make([]*net.IPNet, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = scope.Masked()
+		nets = append(nets, &net.IPNet{IP: scope.Addr().AsSlice(), Mask: net.CIDRMask(scope.Bits(), scope.Addr().BitLen())})
+	}
+
+	group, err := GroupFor(net.IP(session.AsSlice()), nets...)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	addr, _ := netip.AddrFromSlice(group.IP)
+
+	return netip.AddrPortFrom(addr.Unmap(), uint16(group.Port)), nil
+}
+
+// ipv4Group returns the SAP group of an IPv4 multicast session address.
+func ipv4Group(session net.IP, scopes []*net.IPNet) net.IP {
+	if !administrativeScopes.Contains(session) {
+		return GlobalIPv4Group
+	}
+
+	if len(scopes) == 0 {
+		scopes = []*net.IPNet{IPv4LocalScope, IPv4OrganizationLocalScope}
+	}
+
+	for _, scope := range scopes {
+		if scope.Contains(session) {
+			return highestAddress(scope)
+		}
+	}
+
+	return highestAddress(IPv4LocalScope)
+}
+
+// highestAddress returns the last address of n.
+// The address of n may be in its 16-byte form with a 4-byte mask, as net.IPNet.Contains allows.
+func highestAddress(n *net.IPNet) net.IP {
+	network := n.IP.To16()
+	if len(n.Mask) == net.IPv4len {
+		network = n.IP.To4()
+	}
+
+	ip := make(net.IP, len(n.Mask))
+	for i := range n.Mask {
+		ip[i] = network[i] | ^n.Mask[i]
+	}
+
+	return ip
+}
+
+// JoinGroup returns a socket bound to the SAP group and joined to it on the interface ifi,
+// or on the system default interface if ifi is nil.
+// It suits both a Listener and an Announcer, which sends its announcements to the same group.
+// Multicast loopback is enabled, so that the announcements sent with the socket are also heard on the same host.
+func JoinGroup(group *net.UDPAddr, ifi *net.Interface) (*net.UDPConn, error) {
+	network := "udp6"
+	if group.IP.To4() != nil {
+		network = "udp4"
+	}
+
+	conn, err := net.ListenMulticastUDP(network, ifi, group)
+	if err != nil {
+		return nil, err
+	}
+
+	// ListenMulticastUDP turns multicast loopback off
+	if network == "udp4" {
+		err = ipv4.NewPacketConn(conn).SetMulticastLoopback(true)
+	} else {
+		err = ipv6.NewPacketConn(conn).SetMulticastLoopback(true)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,108 @@
+package sap
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestGroupFor(t *testing.T) {
+	_, customScope, _ := net.ParseCIDR("239.16.32.0/23")
+
+	testCases := []struct {
+		name    string
+		session string
+		scopes  []*net.IPNet
+		want    string
+		err     error
+	}{
+		{
+			name:    "Test 1: Global IPv4",
+			session: "224.2.17.12",
+			want:    "224.2.127.254",
+		},
+		{
+			name:    "Test 2: IPv4 Local Scope",
+			session: "239.255.1.2",
+			want:    "239.255.255.255",
+		},
+		{
+			name:    "Test 3: IPv4 Organization Local Scope",
+			session: "239.193.0.1",
+			want:    "239.195.255.255",
+		},
+		{
+			name:    "Test 4: AES67 Range",
+			session: "239.69.1.2",
+			want:    "239.255.255.255",
+		},
+		{
+			name:    "Test 5: Configured Scope",
+			session: "239.16.32.10",
+			scopes:  []*net.IPNet{customScope},
+			want:    "239.16.33.255",
+		},
+		{
+			name:    "Test 6: Configured Scope With A 16-Byte Address",
+			session: "239.16.32.10",
+			scopes:  []*net.IPNet{{IP: customScope.IP.To16(), Mask: customScope.Mask}},
+			want:    "239.16.33.255",
+		},
+		{
+			name:    "Test 7: IPv6 Site Local",
+			session: "ff15::1234",
+			want:    "ff05::2:7ffe",
+		},
+		{
+			name:    "Test 8: IPv6 Global",
+			session: "ff0e::1234",
+			want:    "ff0e::2:7ffe",
+		},
+		{
+			name:    "Test 9: Unicast",
+			session: "192.0.2.1",
+			err:     errNotMulticast,
+		},
+		{
+			name:    "Test 10: Reserved IPv6 Scope",
+			session: "ff00::1234",
+			err:     errReservedScope,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GroupFor(net.ParseIP(tc.session), tc.scopes...)
+			if err != tc.err {
+				t.Fatalf("Expected error %v, but got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if !got.IP.Equal(net.ParseIP(tc.want)) || got.Port != Port {
+				t.Errorf("expected %s:%d, got %v", tc.want, Port, got)
+			}
+		})
+	}
+}
+
+func TestGroupAddrPort(t *testing.T) {
+	got, err := GroupAddrPort(netip.MustParseAddr("239.16.32.10"), netip.MustParsePrefix("239.16.32.0/23"))
+	if err != nil {
+		t.Fatalf("GroupAddrPort failed with error: %v", err)
+	}
+
+	if want := netip.MustParseAddrPort("239.16.33.255:9875"); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got, err = GroupAddrPort(netip.MustParseAddr("224.2.17.12"))
+	if err != nil {
+		t.Fatalf("GroupAddrPort failed with error: %v", err)
+	}
+
+	if want := netip.MustParseAddrPort("224.2.127.254:9875"); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
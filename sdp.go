@@ -0,0 +1,58 @@
+package sap
+
+import (
+	"net"
+
+	"github.com/pion/sdp/v3"
+)
+
+// NewSessionAnnouncement creates an announcement of the session description, see NewPacket
+func NewSessionAnnouncement(desc *sdp.SessionDescription, originatingSource net.UDPAddr, opts ...PacketOption) (Packet, error) {
+	return newSessionPacket(desc, Announcement, originatingSource, opts)
+}
+
+// NewSessionDeletion creates a deletion of the session description, see NewPacket.
+// The whole description is sent so the message id hash matches that of the announcement.
+func NewSessionDeletion(desc *sdp.SessionDescription, originatingSource net.UDPAddr, opts ...PacketOption) (Packet, error) {
+	return newSessionPacket(desc, Deletion, originatingSource, opts)
+}
+
+func newSessionPacket(desc *sdp.SessionDescription, messageType MessageType, originatingSource net.UDPAddr, opts []PacketOption) (Packet, error) {
+	payload, err := desc.Marshal()
+	if err != nil {
+		return Packet{}, err
+	}
+
+	return NewPacket(payload, originatingSource, append([]PacketOption{WithMessageType(messageType)}, opts...)...)
+}
+
+// SessionDescription parses the payload of an application/sdp packet, whether the payload type was sent or omitted.
+// A byte order mark or whitespace in front of the description, as tolerated by PayloadTypePolicy, is ignored.
+// It returns a *PayloadTypeError if the payload is of another type or could not be decrypted.
+func (p Packet) SessionDescription() (*sdp.SessionDescription, error) {
+	if !p.carriesSDP() {
+		return nil, &PayloadTypeError{PayloadType: p.PayloadType}
+	}
+
+	desc := &sdp.SessionDescription{}
+	if err := desc.Unmarshal(trimSDPQuirks(p.Payload)); err != nil {
+		return nil, err
+	}
+
+	return desc, nil
+}
+
+// carriesSDP reports whether the payload is a readable SDP description, the payload type being application/sdp or omitted.
+func (p Packet) carriesSDP() bool {
+	if p.Undecryptable() {
+		return false
+	}
+
+	if p.PayloadType == "" {
+		return true
+	}
+
+	_, ok := (PayloadTypePolicy{}).known([]byte(p.PayloadType))
+
+	return ok
+}
@@ -0,0 +1,122 @@
+package sap
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/pion/sdp/v3"
+)
+
+func createMockSessionDescription() *sdp.SessionDescription {
+	return &sdp.SessionDescription{
+		Origin: sdp.Origin{
+			Username:       "-",
+			SessionID:      1423986,
+			SessionVersion: 1423994,
+			NetworkType:    "IN",
+			AddressType:    "IP4",
+			UnicastAddress: "192.0.2.1",
+		},
+		SessionName: "Session",
+		TimeDescriptions: []sdp.TimeDescription{
+			{Timing: sdp.Timing{StartTime: 0, StopTime: 0}},
+		},
+	}
+}
+
+func TestSessionPackets(t *testing.T) {
+	desc := createMockSessionDescription()
+	source := net.UDPAddr{IP: net.ParseIP("192.0.2.1")}
+
+	announcement, err := NewSessionAnnouncement(desc, source, WithCompression())
+	if err != nil {
+		t.Fatalf("NewSessionAnnouncement failed with error: %v", err)
+	}
+
+	deletion, err := NewSessionDeletion(desc, source)
+	if err != nil {
+		t.Fatalf("NewSessionDeletion failed with error: %v", err)
+	}
+
+	if announcement.MessageType != Announcement || announcement.Compressed != 1 || deletion.MessageType != Deletion {
+		t.Errorf("unexpected message types %d and %d", announcement.MessageType, deletion.MessageType)
+	}
+
+	if announcement.MessageIDHash != deletion.MessageIDHash {
+		t.Errorf("expected the deletion hash %d to match the announcement hash %d", deletion.MessageIDHash, announcement.MessageIDHash)
+	}
+
+	data, err := announcement.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	p := Packet{}
+	if err := p.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	got, err := p.SessionDescription()
+	if err != nil {
+		t.Fatalf("SessionDescription failed with error: %v", err)
+	}
+
+	if got.Origin != desc.Origin || got.SessionName != desc.SessionName {
+		t.Errorf("expected origin %v and name %q, got %v and %q", desc.Origin, desc.SessionName, got.Origin, got.SessionName)
+	}
+}
+
+func TestPacketSessionDescription(t *testing.T) {
+	payload, err := createMockSessionDescription().Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		mockPacket    Packet
+		expectedError *PayloadTypeError
+	}{
+		{
+			name:       "Implicit",
+			mockPacket: Packet{Payload: payload},
+		},
+		{
+			name:       "Explicit",
+			mockPacket: Packet{Header: Header{PayloadType: "application/sdp"}, Payload: payload},
+		},
+		{
+			name:       "ExplicitWithParameters",
+			mockPacket: Packet{Header: Header{PayloadType: "Application/SDP; charset=UTF-8"}, Payload: payload},
+		},
+		{
+			name:          "OtherPayloadType",
+			mockPacket:    Packet{Header: Header{PayloadType: "text/plain"}, Payload: []byte("hello")},
+			expectedError: &PayloadTypeError{PayloadType: "text/plain"},
+		},
+		{
+			name:          "Undecryptable",
+			mockPacket:    Packet{Header: Header{Encrypted: 1}, Encryption: &Encryption{KeyID: 1}, Payload: []byte("ciphertext")},
+			expectedError: &PayloadTypeError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			desc, err := tc.mockPacket.SessionDescription()
+
+			var payloadTypeErr *PayloadTypeError
+			if tc.expectedError == nil {
+				if err != nil {
+					t.Fatalf("SessionDescription failed with error: %v", err)
+				}
+				if desc.SessionName != "Session" {
+					t.Errorf("expected session name %q, got %q", "Session", desc.SessionName)
+				}
+			} else if !errors.As(err, &payloadTypeErr) || *payloadTypeErr != *tc.expectedError {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
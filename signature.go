@@ -0,0 +1,105 @@
+package sap
+
+import (
+	"fmt"
+)
+
+// Signer signs outgoing packets. The signature is placed in the authentication header.
+type Signer interface {
+	// AuthType returns the format of the signatures produced by Sign
+	AuthType() AuthType
+
+	// Sign returns the signature of data
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier checks the signature of received packets against a set of trusted keys.
+type Verifier interface {
+	// AuthType returns the format of the signatures checked by Verify
+	AuthType() AuthType
+
+	// Verify returns an error unless signature is a valid signature of data by a trusted key
+	Verify(data, signature []byte) error
+}
+
+// Only accept packets signed by a key trusted by one of the verifiers.
+// Each verifier handles the signatures of its AuthType, packets without an
+// authentication header or with a signature of another format are rejected.
+func WithVerifiers(verifiers ...Verifier) UnmarshalOption {
+	return func(c *unmarshalConfig) {
+		c.verifiers = append(c.verifiers, verifiers...)
+	}
+}
+
+// sign signs the packet and stores the signature in the authentication header.
+// body is what follows the header on the wire, as returned by p.body().
+//
+// The signature covers the whole packet as it is sent, except for the
+// authentication data which is left out and the authentication length which is set to zero.
+func (p *Packet) sign(body []byte) error {
+	unsigned := p.Header
+	unsigned.AuthenticationLength = 0
+	unsigned.AuthenticationData = nil
+
+	buf := make([]byte, unsigned.MarshalSize()+len(body))
+	n, err := unsigned.MarshalTo(buf)
+	if err != nil {
+		return err
+	}
+	copy(buf[n:], body)
+
+	signature, err := p.Signer.Sign(buf)
+	if err != nil {
+		return err
+	}
+
+	return p.Header.SetAuthHeader(AuthHeader{
+		Version:   1,
+		AuthType:  p.Signer.AuthType(),
+		Signature: signature,
+	})
+}
+
+// verify checks the signature of buf, the packet h was unmarshaled from,
+// with the verifier matching the format of the signature.
+func verify(buf []byte, h Header, verifiers []Verifier) error {
+	// The authentication data follows the fixed 4 bytes and the originating source
+	authStart := 4 + 4
+	if h.AddressType == IPv6 {
+		authStart = 4 + 16
+	}
+	authEnd := authStart + int(h.AuthenticationLength)*4
+
+	if h.AuthenticationLength == 0 {
+		return malformed(ErrNotAuthenticated, "Authentication Length", 1)
+	}
+
+	authHeader, err := h.AuthHeader()
+	if err != nil {
+		return malformed(err, "Authentication Data", authStart)
+	}
+
+	var verifier Verifier
+	for _, v := range verifiers {
+		if v.AuthType() == authHeader.AuthType {
+			verifier = v
+			break
+		}
+	}
+	if verifier == nil {
+		return malformed(ErrNoVerifierForAuthType, "Authentication Data", authStart)
+	}
+
+	unsigned := make([]byte, 0, len(buf)-(authEnd-authStart))
+	unsigned = append(unsigned, buf[:authStart]...)
+	unsigned = append(unsigned, buf[authEnd:]...)
+
+	// Authentication Length
+	unsigned[1] = 0
+
+	if err := verifier.Verify(unsigned, authHeader.Signature); err != nil {
+		return malformed(fmt.Errorf("%w: %w", ErrInvalidSignature, err), "Authentication Data", authStart)
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package sap
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"net"
+	"testing"
+)
+
+// mockSigner signs with an Ed25519 key and pretends to produce signatures of the given format.
+type mockSigner struct {
+	authType AuthType
+	key      ed25519.PrivateKey
+}
+
+func (s mockSigner) AuthType() AuthType {
+	return s.authType
+}
+
+func (s mockSigner) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+func (s mockSigner) Verify(data, signature []byte) error {
+	if !ed25519.Verify(s.key.Public().(ed25519.PublicKey), data, signature) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func TestPacketSignAndVerify(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed with error: %v", err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed with error: %v", err)
+	}
+
+	signer := mockSigner{authType: PGP, key: key}
+	payload := []byte("v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=Signed\r\n")
+
+	testCases := []struct {
+		name   string
+		source string
+		opts   []PacketOption
+	}{
+		{
+			name:   "Test 1: IPv4",
+			source: "192.0.2.1",
+		},
+		{
+			name:   "Test 2: IPv6 Deletion",
+			source: "2001:db8::68",
+			opts:   []PacketOption{WithMessageType(Deletion)},
+		},
+		{
+			name:   "Test 3: Compressed With Payload Type",
+			source: "192.0.2.1",
+			opts:   []PacketOption{WithCompression(), WithPayloadType("application/sdp")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewPacket(payload, net.UDPAddr{IP: net.ParseIP(tc.source)}, append(tc.opts, WithSigner(signer))...)
+			if err != nil {
+				t.Fatalf("NewPacket failed with error: %v", err)
+			}
+
+			data, err := p.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed with error: %v", err)
+			}
+
+			p2 := &Packet{}
+			if err := p2.Unmarshal(data, WithVerifiers(signer)); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			authHeader, err := p2.AuthHeader()
+			if err != nil {
+				t.Fatalf("AuthHeader failed with error: %v", err)
+			}
+			if authHeader.AuthType != PGP || len(authHeader.Signature) != ed25519.SignatureSize {
+				t.Errorf("unexpected authentication header %+v", authHeader)
+			}
+
+			// Flip a bit of the payload
+			tampered := append([]byte{}, data...)
+			tampered[len(tampered)-1] ^= 0x01
+			if err := p2.Unmarshal(tampered, WithVerifiers(signer)); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected error %v, but got %v", ErrInvalidSignature, err)
+			}
+
+			if err := p2.Unmarshal(data, WithVerifiers(mockSigner{authType: PGP, key: otherKey})); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected error %v, but got %v", ErrInvalidSignature, err)
+			}
+		})
+	}
+}
+
+func TestPacketVerifyErrors(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed with error: %v", err)
+	}
+
+	unsigned, err := CreateMockPacket(Packet{Payload: []byte("v=0\r\n")}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	signed := CreateMockPacket(Packet{Payload: []byte("v=0\r\n")})
+	signed.Signer = mockSigner{authType: CMS, key: key}
+	cmsSigned, err := signed.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		input         []byte
+		expectedError error
+	}{
+		{
+			name:          "NotAuthenticated",
+			input:         unsigned,
+			expectedError: ErrNotAuthenticated,
+		},
+		{
+			name:          "NoVerifierForAuthType",
+			input:         cmsSigned,
+			expectedError: ErrNoVerifierForAuthType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Packet{}
+			err := p.Unmarshal(tc.input, WithVerifiers(mockSigner{authType: PGP, key: key}))
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+// growingSigner returns a longer signature every time it signs, like DER encoded ECDSA signatures can.
+type growingSigner struct {
+	signatures *int
+}
+
+func (s growingSigner) AuthType() AuthType {
+	return CMS
+}
+
+func (s growingSigner) Sign(data []byte) ([]byte, error) {
+	*s.signatures++
+	return make([]byte, 4**s.signatures), nil
+}
+
+func TestPreparedPacket(t *testing.T) {
+	signatures := 0
+	p, err := NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, WithSigner(growingSigner{signatures: &signatures}))
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	// Sizing the packet does not sign it
+	if size := p.MarshalSize(); size != 8+len("v=0\r\n") || signatures != 0 {
+		t.Errorf("expected an unsigned size of %d, got %d after %d signatures", 8+len("v=0\r\n"), size, signatures)
+	}
+
+	prepared, err := p.Prepare()
+	if err != nil {
+		t.Fatalf("Prepare failed with error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		buf := make([]byte, prepared.MarshalSize())
+		n, err := prepared.MarshalTo(buf)
+		if err != nil {
+			t.Fatalf("MarshalTo failed with error: %v", err)
+		}
+		if n != len(buf) {
+			t.Errorf("expected %d bytes, got %d", len(buf), n)
+		}
+	}
+
+	if signatures != 1 {
+		t.Errorf("expected the packet to be signed once, got %d signatures", signatures)
+	}
+
+	data, err := prepared.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	p2 := &Packet{}
+	if err := p2.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed with error: %v", err)
+	}
+
+	if p2.AuthenticationLength != prepared.Packet.AuthenticationLength {
+		t.Errorf("expected authentication length %d, got %d", prepared.Packet.AuthenticationLength, p2.AuthenticationLength)
+	}
+}
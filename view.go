@@ -0,0 +1,222 @@
+package sap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"strings"
+)
+
+// PacketView is a read-only view of an SAP packet over the buffer it was received in.
+// Unlike Packet.Unmarshal, it does not allocate: the accessors decode the fields
+// on demand and the returned slices point into the buffer, which must not be modified while the view is in use.
+//
+// The payload of a compressed or encrypted packet is returned as is, use Packet.Unmarshal to decode it.
+type PacketView struct {
+	buf []byte
+
+	// Where the authentication data, the payload type and the payload start, and where the payload type ends
+	authStart        int
+	payloadTypeStart int
+	payloadTypeEnd   int
+	payloadStart     int
+}
+
+// ViewPacket checks that buf holds a well formed SAP header and returns a view over it.
+// The payload type is detected with DefaultPayloadTypePolicy, so it returns the same errors as Header.Unmarshal.
+func ViewPacket(buf []byte) (PacketView, error) {
+	if len(buf) < 1 {
+		return PacketView{}, truncated(ErrBufTooSmallForFlags, "Flags", buf, 0, 1)
+	}
+	if len(buf) < 2 {
+		return PacketView{}, truncated(ErrBufTooSmallForAuthLength, "Authentication Length", buf, 1, 1)
+	}
+	if len(buf) < 4 {
+		return PacketView{}, truncated(ErrBufTooSmallForMsgIdHash, "Message Identifier Hash", buf, 2, 2)
+	}
+
+	v := PacketView{buf: buf, authStart: 4 + 4}
+
+	if v.Version() > 1 {
+		return PacketView{}, malformed(ErrInvalidVersion, "Flags", 0)
+	}
+
+	if v.Version() == 0 && v.AddressType() != IPv4 {
+		return PacketView{}, malformed(ErrLegacyIPv6, "Flags", 0)
+	}
+
+	if v.AddressType() == IPv6 {
+		v.authStart = 4 + 16
+		if len(buf) < v.authStart {
+			return PacketView{}, truncated(ErrBufTooSmallForIPv6, "Originating Source", buf, 4, 16)
+		}
+	} else if len(buf) < v.authStart {
+		return PacketView{}, truncated(ErrBufTooSmallForIPv4, "Originating Source", buf, 4, 4)
+	}
+
+	authLength := int(v.AuthenticationLength()) * 4
+	if len(buf) < v.authStart+authLength {
+		return PacketView{}, truncated(ErrBufTooSmallForAuthData, "Authentication Data", buf, v.authStart, authLength)
+	}
+
+	v.payloadTypeStart = v.authStart + authLength
+	v.payloadTypeEnd = v.payloadTypeStart
+	v.payloadStart = v.payloadTypeStart
+
+	// SAPv0 packets have no payload type
+	if v.Version() != 0 && v.Compressed() == 0 && v.Encrypted() == 0 {
+		length, n, err := DefaultPayloadTypePolicy.view(buf[v.payloadTypeStart:])
+		if err != nil {
+			return PacketView{}, malformed(err, "Payload Type", v.payloadTypeStart)
+		}
+		v.payloadTypeEnd += length
+		v.payloadStart += n
+	}
+
+	return v, nil
+}
+
+// view is an allocation free counterpart of detect, where the payload type is checked with isMediaType.
+// It returns the length of the payload type and the number of bytes it takes up, including its terminator.
+func (pol PayloadTypePolicy) view(buf []byte) (length int, n int, err error) {
+	if len(buf) < 3 || isSDP(buf) {
+		// no payload or the payload type has been omitted
+		return 0, 0, nil
+	}
+
+	i := bytes.IndexByte(buf, 0)
+	if i >= 0 {
+		if _, ok := pol.known(buf[:i]); ok {
+			return i, i + 1, nil
+		}
+
+		if !isMediaType(buf[:i]) {
+			err = ErrInvalidPayloadType
+		} else if pol.OnlyKnownTypes {
+			err = ErrUnknownPayloadType
+		} else {
+			return i, i + 1, nil
+		}
+	} else {
+		err = ErrNoTrailingByteFound
+	}
+
+	if pol.TolerateQuirks {
+		if isSDP(trimSDPQuirks(buf)) {
+			return 0, 0, nil
+		}
+
+		if j := bytes.IndexByte(buf, '\n'); j >= 0 && (i < 0 || j < i) {
+			line := bytes.TrimRight(buf[:j], "\r")
+			if _, ok := pol.known(line); ok {
+				return len(line), j + 1, nil
+			}
+		}
+	}
+
+	return 0, 0, err
+}
+
+// isMediaType reports whether b starts with a type or type/subtype media type, optionally followed by parameters.
+// The parameters are not checked.
+func isMediaType(b []byte) bool {
+	if i := bytes.IndexByte(b, ';'); i >= 0 {
+		b = b[:i]
+	}
+	b = bytes.TrimSpace(b)
+
+	slash := bytes.IndexByte(b, '/')
+	if slash < 0 {
+		return isToken(b)
+	}
+
+	return isToken(b[:slash]) && isToken(b[slash+1:])
+}
+
+// tspecials are the characters that cannot appear in a token (RFC 1521 section 4)
+const tspecials = `()<>@,;:\"/[]?=`
+
+// isToken reports whether b is a token as defined by RFC 1521.
+func isToken(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	for _, c := range b {
+		if c <= ' ' || c >= 0x7F || strings.IndexByte(tspecials, c) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Version returns the version number field.
+func (v PacketView) Version() uint8 {
+	return v.buf[0] >> versionShift
+}
+
+// AddressType returns the address type of the originating source.
+func (v PacketView) AddressType() AddressType {
+	return AddressType(v.buf[0]>>addressShift) & oneBitMask
+}
+
+// Reserved returns the reserved bit.
+func (v PacketView) Reserved() uint8 {
+	return (v.buf[0] >> reservedShift) & oneBitMask
+}
+
+// MessageType returns whether the packet is an announcement or a deletion.
+func (v PacketView) MessageType() MessageType {
+	return MessageType(v.buf[0]>>messageTypeShift) & oneBitMask
+}
+
+// Encrypted returns the encrypted bit.
+func (v PacketView) Encrypted() uint8 {
+	return (v.buf[0] >> encryptedShift) & oneBitMask
+}
+
+// Compressed returns the compressed bit.
+func (v PacketView) Compressed() uint8 {
+	return (v.buf[0] >> compressedShift) & oneBitMask
+}
+
+// AuthenticationLength returns the number of 32 bit words of authentication data.
+func (v PacketView) AuthenticationLength() uint8 {
+	return v.buf[1]
+}
+
+// MessageIDHash returns the message identifier hash.
+func (v PacketView) MessageIDHash() uint16 {
+	return binary.BigEndian.Uint16(v.buf[2:4])
+}
+
+// OriginatingSource returns the address of the originating source.
+func (v PacketView) OriginatingSource() netip.Addr {
+	if v.AddressType() == IPv6 {
+		return netip.AddrFrom16(*(*[16]byte)(v.buf[4:20]))
+	}
+
+	return netip.AddrFrom4(*(*[4]byte)(v.buf[4:8]))
+}
+
+// AuthenticationData returns the raw authentication data.
+func (v PacketView) AuthenticationData() []byte {
+	return v.buf[v.authStart:v.payloadTypeStart]
+}
+
+// PayloadType returns the payload type as sent, without its trailing zero byte or line break.
+// It is empty if the payload type was omitted or is compressed or encrypted with the payload.
+// Unlike Header.PayloadType, it is not normalized to lower case.
+func (v PacketView) PayloadType() []byte {
+	if v.payloadTypeEnd == v.payloadTypeStart {
+		return nil
+	}
+
+	return v.buf[v.payloadTypeStart:v.payloadTypeEnd]
+}
+
+// Payload returns the payload, compressed and/or encrypted if the packet is.
+func (v PacketView) Payload() []byte {
+	return v.buf[v.payloadStart:]
+}
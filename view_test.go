@@ -0,0 +1,237 @@
+package sap
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+// mockViewPacket returns a marshaled IPv6 packet with authentication data and a payload type.
+func mockViewPacket(t testing.TB) []byte {
+	p := CreateMockPacket(Packet{
+		Header: Header{
+			AddressType:          IPv6,
+			AuthenticationLength: 2,
+			MessageIDHash:        0x1234,
+			PayloadType:          "application/sdp",
+		},
+		Payload: []byte("v=0\r\no=- 1234 1 IN IP6 2001:db8::68\r\n"),
+	})
+	p.AuthenticationData = []uint32{0x01020304, 0x05060708}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	return data
+}
+
+func TestViewPacket(t *testing.T) {
+	v, err := ViewPacket(mockViewPacket(t))
+	if err != nil {
+		t.Fatalf("ViewPacket failed with error: %v", err)
+	}
+
+	if v.Version() != 1 || v.AddressType() != IPv6 || v.MessageType() != Announcement || v.Encrypted() != 0 || v.Compressed() != 0 {
+		t.Errorf("unexpected flags %d %d %d %d %d", v.Version(), v.AddressType(), v.MessageType(), v.Encrypted(), v.Compressed())
+	}
+	if v.MessageIDHash() != 0x1234 {
+		t.Errorf("expected hash %#x, got %#x", 0x1234, v.MessageIDHash())
+	}
+	if want := netip.MustParseAddr("2001:db8::68"); v.OriginatingSource() != want {
+		t.Errorf("expected source %v, got %v", want, v.OriginatingSource())
+	}
+	if want := []byte{1, 2, 3, 4, 5, 6, 7, 8}; !bytes.Equal(v.AuthenticationData(), want) {
+		t.Errorf("expected authentication data %v, got %v", want, v.AuthenticationData())
+	}
+	if string(v.PayloadType()) != "application/sdp" {
+		t.Errorf("unexpected payload type %q", v.PayloadType())
+	}
+	if string(v.Payload()) != "v=0\r\no=- 1234 1 IN IP6 2001:db8::68\r\n" {
+		t.Errorf("unexpected payload %q", v.Payload())
+	}
+}
+
+func TestViewPacketOmittedPayloadType(t *testing.T) {
+	p, err := NewPacket([]byte("v=0\r\n"), net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("NewPacket failed with error: %v", err)
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed with error: %v", err)
+	}
+
+	v, err := ViewPacket(data)
+	if err != nil {
+		t.Fatalf("ViewPacket failed with error: %v", err)
+	}
+
+	if want := netip.MustParseAddr("192.0.2.1"); v.OriginatingSource() != want {
+		t.Errorf("expected source %v, got %v", want, v.OriginatingSource())
+	}
+	if v.PayloadType() != nil {
+		t.Errorf("expected no payload type, got %q", v.PayloadType())
+	}
+	if string(v.Payload()) != "v=0\r\n" {
+		t.Errorf("unexpected payload %q", v.Payload())
+	}
+}
+
+// TestViewPacketQuirks checks that the view accepts the packets Packet.Unmarshal accepts by tolerating a quirk.
+func TestViewPacketQuirks(t *testing.T) {
+	testCases := []struct {
+		name                string
+		input               string
+		expectedPayloadType string
+		expectedPayload     string
+	}{
+		{
+			name:            "ByteOrderMark",
+			input:           "\xEF\xBB\xBFv=0\r\n",
+			expectedPayload: "\xEF\xBB\xBFv=0\r\n",
+		},
+		{
+			name:            "LeadingWhitespace",
+			input:           "\r\n v=0\r\n",
+			expectedPayload: "\r\n v=0\r\n",
+		},
+		{
+			name:                "PayloadTypeEndedByLineBreak",
+			input:               "application/sdp\r\nv=0\r\n",
+			expectedPayloadType: "application/sdp",
+			expectedPayload:     "v=0\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := append([]byte{0x20, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01}, tc.input...)
+
+			p := Packet{}
+			if err := p.Unmarshal(data); err != nil {
+				t.Fatalf("Unmarshal failed with error: %v", err)
+			}
+
+			v, err := ViewPacket(data)
+			if err != nil {
+				t.Fatalf("ViewPacket failed with error: %v", err)
+			}
+
+			if string(v.PayloadType()) != tc.expectedPayloadType {
+				t.Errorf("expected payload type %q, got %q", tc.expectedPayloadType, v.PayloadType())
+			}
+			if string(v.Payload()) != tc.expectedPayload || !bytes.Equal(v.Payload(), p.Payload) {
+				t.Errorf("expected payload %q, got %q", tc.expectedPayload, v.Payload())
+			}
+		})
+	}
+}
+
+func TestViewPacketErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         []byte
+		expectedError error
+	}{
+		{
+			name:          "BufTooSmallForFlags",
+			input:         []byte{},
+			expectedError: ErrBufTooSmallForFlags,
+		},
+		{
+			name:          "BufTooSmallForMsgIdHash",
+			input:         []byte{0x20, 0x00, 0x00},
+			expectedError: ErrBufTooSmallForMsgIdHash,
+		},
+		{
+			name:          "BufTooSmallForIPv6",
+			input:         []byte{0x30, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			expectedError: ErrBufTooSmallForIPv6,
+		},
+		{
+			name:          "BufTooSmallForAuthData",
+			input:         []byte{0x20, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			expectedError: ErrBufTooSmallForAuthData,
+		},
+		{
+			name:          "InvalidVersion",
+			input:         []byte{0x40, 0x00, 0x12, 0x34, 0xC0, 0x00, 0x02, 0x01, 'v', '=', '0'},
+			expectedError: ErrInvalidVersion,
+		},
+		{
+			name:          "NoTrailingByteFound",
+			input:         []byte{0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0x01},
+			expectedError: ErrNoTrailingByteFound,
+		},
+		{
+			name:          "InvalidPayloadType",
+			input:         []byte{0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'a', ' ', 'b', 0x00},
+			expectedError: ErrInvalidPayloadType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ViewPacket(tc.input)
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error %v, but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestViewPacketAllocs(t *testing.T) {
+	data := mockViewPacket(t)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		v, _ := ViewPacket(data)
+		_ = v.OriginatingSource()
+		_ = v.AuthenticationData()
+		_ = v.PayloadType()
+		_ = v.Payload()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+
+	quirk := []byte("\x20\x00\x12\x34\xC0\x00\x02\x01application/sdp\r\nv=0\r\n")
+	allocs = testing.AllocsPerRun(100, func() {
+		v, _ := ViewPacket(quirk)
+		_ = v.PayloadType()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations with a quirk, got %v", allocs)
+	}
+}
+
+func BenchmarkViewPacket(b *testing.B) {
+	data := mockViewPacket(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v, err := ViewPacket(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = v.OriginatingSource()
+		_ = v.PayloadType()
+		_ = v.Payload()
+	}
+}
+
+func BenchmarkPacketUnmarshal(b *testing.B) {
+	data := mockViewPacket(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := Packet{}
+		if err := p.Unmarshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
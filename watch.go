@@ -0,0 +1,193 @@
+package sap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pion/sdp/v3"
+)
+
+// defaultPollInterval is how often a FolderWatcher looks for changes when no interval is given
+const defaultPollInterval = 2 * time.Second
+
+// FolderWatcher announces the SDP files of a folder with an Announcer. A new file is announced,
+// a modified file is announced again, followed by a deletion of its previous version, and a removed file is deleted.
+// The folder is polled, which works on every platform and file system, network shares included.
+type FolderWatcher struct {
+	// ErrorHandler, if not nil, is called with the errors of the files that could not be announced,
+	// updated or deleted. They are looked at again on the next sync.
+	ErrorHandler func(error)
+
+	announcer         *Announcer
+	dir               string
+	originatingSource net.UDPAddr
+	opts              []PacketOption
+
+	files map[string]*watchedFile
+}
+
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	payload []byte
+	session *Session
+}
+
+// NewFolderWatcher returns a FolderWatcher announcing the files of dir ending in .sdp with a.
+// The packets are created by NewPacket with originatingSource and opts.
+func NewFolderWatcher(a *Announcer, dir string, originatingSource net.UDPAddr, opts ...PacketOption) *FolderWatcher {
+	return &FolderWatcher{
+		announcer:         a,
+		dir:               dir,
+		originatingSource: originatingSource,
+		opts:              opts,
+		files:             map[string]*watchedFile{},
+	}
+}
+
+// Run syncs the folder every interval, or every 2 seconds if interval is zero, until ctx is done or
+// the folder cannot be read. The sessions are still announced when it returns, until the Announcer is closed.
+func (w *FolderWatcher) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Sync(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Sync brings the sessions in line with the files of the folder. It returns an error if the folder cannot be read.
+// Files that cannot be read or are not valid SDP descriptions, such as files still being written, are skipped
+// and looked at again on the next call.
+func (w *FolderWatcher) Sync() error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(name), ".sdp") {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		w.syncFile(name, entry)
+	}
+
+	for name, f := range w.files {
+		if _, ok := seen[name]; !ok {
+			if err := w.announcer.Delete(f.session); err != nil {
+				w.report(fmt.Errorf("deleting %s: %w", name, err))
+			}
+			delete(w.files, name)
+		}
+	}
+
+	return nil
+}
+
+// syncFile announces the file if it is new, or its new version if it was modified.
+func (w *FolderWatcher) syncFile(name string, entry os.DirEntry) {
+	info, err := entry.Info()
+	if err != nil {
+		return
+	}
+
+	f, ok := w.files[name]
+	if ok && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return
+	}
+
+	payload, err := readSDPFile(filepath.Join(w.dir, name))
+	if err != nil {
+		return
+	}
+
+	if ok && bytes.Equal(payload, f.payload) {
+		f.modTime, f.size = info.ModTime(), info.Size()
+		return
+	}
+
+	p, err := NewPacket(payload, w.originatingSource, w.opts...)
+	if err != nil {
+		w.report(fmt.Errorf("announcing %s: %w", name, err))
+		return
+	}
+
+	if ok {
+		err := w.announcer.Update(f.session, p)
+		if err != nil {
+			w.report(fmt.Errorf("updating %s: %w", name, err))
+		}
+
+		if errors.Is(err, errUnknownSession) {
+			// The session was removed from the announcer, the file is announced as a new one
+			ok = false
+		} else if err != nil {
+			return
+		}
+	}
+
+	if !ok {
+		s, err := w.announcer.Add(p)
+		if err != nil {
+			w.report(fmt.Errorf("announcing %s: %w", name, err))
+			return
+		}
+		f = &watchedFile{session: s}
+		w.files[name] = f
+	}
+
+	f.modTime, f.size, f.payload = info.ModTime(), info.Size(), payload
+}
+
+// report passes err to the ErrorHandler, if any.
+func (w *FolderWatcher) report(err error) {
+	if w.ErrorHandler != nil {
+		w.ErrorHandler(err)
+	}
+}
+
+// readSDPFile reads a complete SDP description, with its line endings turned into CRLF as SDP requires.
+func readSDPFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+
+	desc := &sdp.SessionDescription{}
+	if err := desc.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	// A description missing its mandatory fields is likely still being written
+	if desc.SessionName == "" || len(desc.TimeDescriptions) == 0 {
+		return nil, errIncompleteSDP
+	}
+
+	return data, nil
+}
@@ -0,0 +1,164 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFolderWatcher(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr(), WithDeletionRetries(0, 0))
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = a.Close(ctx)
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stream.sdp")
+	first := "v=0\no=- 1 1 IN IP4 192.0.2.1\ns=First\nt=0 0\n"
+	second := "v=0\no=- 1 2 IN IP4 192.0.2.1\ns=Second\nt=0 0\n"
+
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile failed with error: %v", err)
+		}
+	}
+
+	w := NewFolderWatcher(a, dir, net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+
+	// Files that are not SDP descriptions are ignored
+	writeFile("notes.txt", first)
+	writeFile("partial.sdp", "v=0\no=- 1 1 IN IP4 192.0.2.1\n")
+	writeFile("stream.sdp", first)
+
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+
+	announcement := readPacket(t, group)
+	if announcement.MessageType != Announcement || string(announcement.Payload) != string(crlf(first)) {
+		t.Fatalf("unexpected announcement %v", announcement)
+	}
+
+	if len(w.files) != 1 {
+		t.Errorf("expected 1 watched file, got %d", len(w.files))
+	}
+
+	writeFile("stream.sdp", second)
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+
+	previous := announcement
+	announcement = readPacket(t, group)
+	if announcement.MessageType != Announcement || string(announcement.Payload) != string(crlf(second)) {
+		t.Errorf("unexpected announcement %v", announcement)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Deletion || got.MessageIDHash != previous.MessageIDHash {
+		t.Errorf("unexpected deletion %v", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove failed with error: %v", err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Deletion || got.MessageIDHash != announcement.MessageIDHash {
+		t.Errorf("unexpected deletion %v", got)
+	}
+
+	if len(w.files) != 0 {
+		t.Errorf("expected no watched files, got %d", len(w.files))
+	}
+}
+
+// crlf returns s with CRLF line endings
+func crlf(s string) string {
+	out := ""
+	for _, c := range s {
+		if c == '\n' {
+			out += "\r"
+		}
+		out += string(c)
+	}
+	return out
+}
+
+func TestFolderWatcherRemovedSession(t *testing.T) {
+	group, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+	defer group.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed with error: %v", err)
+	}
+
+	a := NewAnnouncer(conn, group.LocalAddr(), WithDeletionRetries(0, 0))
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = a.Close(ctx)
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stream.sdp")
+	if err := os.WriteFile(path, []byte("v=0\no=- 1 1 IN IP4 192.0.2.1\ns=First\nt=0 0\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed with error: %v", err)
+	}
+
+	var errs []error
+	w := NewFolderWatcher(a, dir, net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+	w.ErrorHandler = func(err error) {
+		errs = append(errs, err)
+	}
+
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+	readPacket(t, group)
+
+	// The session is removed behind the back of the watcher
+	if err := a.Remove(w.files["stream.sdp"].session); err != nil {
+		t.Fatalf("Remove failed with error: %v", err)
+	}
+
+	second := "v=0\no=- 1 2 IN IP4 192.0.2.1\ns=Second\nt=0 0\n"
+	if err := os.WriteFile(path, []byte(second), 0o644); err != nil {
+		t.Fatalf("WriteFile failed with error: %v", err)
+	}
+
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+
+	if got := readPacket(t, group); got.MessageType != Announcement || string(got.Payload) != crlf(second) {
+		t.Errorf("unexpected announcement %v", got)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], errUnknownSession) {
+		t.Errorf("Expected error %v, but got %v", errUnknownSession, errs)
+	}
+}